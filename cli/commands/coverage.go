@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+
+	"kurtestosis/cli/core"
+)
+
+// Creates the coverage collector if coverage was requested, returns nil otherwise
+func createCoverage(project *core.KurtestosisProject) (*core.Coverage, error) {
+	if coverageStr == "" {
+		return nil, nil
+	}
+
+	// All the test files are excluded from coverage, not just the ones that will run.
+	// Since test files that do not match a custom pattern can still be test files, the default pattern is used as well
+	testFiles := []*core.TestFile{}
+	for _, pattern := range []string{testFilePatternStr, KurtestosisDefaultTestFilePattern} {
+		patternTestFiles, testFilesErr := core.ListMatchingTestFiles(project, pattern)
+		if testFilesErr != nil {
+			return nil, fmt.Errorf("error matching test files in project: %w", testFilesErr)
+		}
+
+		testFiles = append(testFiles, patternTestFiles...)
+	}
+
+	coverage := core.NewCoverage(project, testFiles)
+
+	// Modules that never get loaded by the tests are reported as not covered at all
+	err := coverage.RegisterProjectModules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to register project modules for coverage: %w", err)
+	}
+
+	return coverage, nil
+}
+
+// Presents the per-file coverage summary and writes the lcov report
+func writeCoverage(coverage *core.Coverage, output testOutput) error {
+	if coverage == nil {
+		return nil
+	}
+
+	output.CoverageFinished(coverage.Summaries())
+
+	return coverage.WriteLcovReport(coverageStr)
+}
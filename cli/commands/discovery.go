@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+
+	"kurtestosis/cli/core"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Finds all the test functions in a project that match the CLI test patterns and the test selector
+//
+// The test functions are returned in a stable order, grouped by their test files.
+// Functions that were found in the test files but will not be run are returned along with the reason why
+func listTestFunctions(project *core.KurtestosisProject, testSelector *core.TestSelector) ([]*core.TestFunction, []*core.SkippedTestFunction, error) {
+	// Let's first get the list of matching test files
+	testFiles, testFilesErr := core.ListMatchingTestFiles(project, testFilePatternStr)
+	if testFilesErr != nil {
+		logrus.Errorf("Error matching test files in project: %v", testFilesErr)
+
+		return nil, nil, fmt.Errorf("error matching test files in project: %w", testFilesErr)
+	}
+
+	// Exit if there are no test suites to run
+	if len(testFiles) == 0 {
+		logrus.Warn("No test suites found matching the glob pattern")
+
+		return nil, nil, nil
+	}
+
+	testFunctions := []*core.TestFunction{}
+	skippedTestFunctions := []*core.SkippedTestFunction{}
+	for _, testFile := range testFiles {
+		// We parse the test file and extract the names of matching test functions
+		testFileFunctions, testFileSkippedFunctions, testFileFunctionsErr := core.ListMatchingTests(testFile, testPatternStr)
+		if testFileFunctionsErr != nil {
+			logrus.Errorf("Failed to list matching test functions in %s: %v", testFile, testFileFunctionsErr)
+
+			return nil, nil, fmt.Errorf("failed to list matching test functions in %s: %w", testFile, testFileFunctionsErr)
+		}
+
+		skippedTestFunctions = append(skippedTestFunctions, testFileSkippedFunctions...)
+
+		if len(testFileFunctions) == 0 {
+			logrus.Warnf("No tests found matching the test pattern %s in %s", testPatternStr, testFile)
+
+			continue
+		}
+
+		selectedTestFunctions, unselectedTestFunctions := testSelector.Filter(testFileFunctions)
+
+		testFunctions = append(testFunctions, selectedTestFunctions...)
+		skippedTestFunctions = append(skippedTestFunctions, unselectedTestFunctions...)
+	}
+
+	// If any of the selected tests are focused, only those will run
+	//
+	// Focus markers are easy to forget about so we always let the user know
+	focusedTestFunctions, unfocusedTestFunctions := core.FilterFocusedTests(testFunctions)
+	if len(unfocusedTestFunctions) > 0 {
+		logrus.Warnf("Running %d focused tests only, skipping %d unfocused tests", len(focusedTestFunctions), len(unfocusedTestFunctions))
+	}
+
+	testFunctions = focusedTestFunctions
+	skippedTestFunctions = append(skippedTestFunctions, unfocusedTestFunctions...)
+
+	// We let the user know about any test IDs that did not match anything
+	for _, testID := range testSelector.UnmatchedTestIDs() {
+		logrus.Warnf("No tests found matching %s", testID)
+	}
+
+	return testFunctions, skippedTestFunctions, nil
+}
+
+// Splits a list of test functions into lists of consecutive test functions from the same test file
+func groupTestFunctionsByFile(testFunctions []*core.TestFunction) [][]*core.TestFunction {
+	groups := [][]*core.TestFunction{}
+	for i, testFunction := range testFunctions {
+		if i == 0 || testFunctions[i-1].TestFile != testFunction.TestFile {
+			groups = append(groups, []*core.TestFunction{})
+		}
+
+		groups[len(groups)-1] = append(groups[len(groups)-1], testFunction)
+	}
+
+	return groups
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"kurtestosis/cli/core"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ListCmd Suppressing exhaustruct requirement because this struct has ~40 properties
+// nolint: exhaustruct
+var ListCmd = &cobra.Command{
+	Use:   "list <path to kurtosis project> [test IDs...]",
+	Short: "Lists the tests in a kurtosis project without running them",
+	Long: "Lists the IDs of the tests that would be run, one per line.\n" +
+		"Functions found in test files that would not be run are listed too, along with the reason why they are skipped",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          list,
+	Args:          cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+}
+
+// listedTest is the JSON representation of a single test in the output of the list command
+type listedTest struct {
+	Test    string
+	File    string
+	Skipped bool   `json:",omitempty"`
+	Reason  string `json:",omitempty"`
+
+	// Line of the test function definition in its test file
+	line int32
+}
+
+func init() {
+	RootCmd.AddCommand(ListCmd)
+}
+
+func list(cmd *cobra.Command, args []string) error {
+	// First we load the project
+	projectPath := args[0]
+	project, projectErr := core.LoadKurtestosisProject(projectPath)
+	if projectErr != nil {
+		logrus.Errorf("Failed to load project from %s: %v", projectPath, projectErr)
+
+		return fmt.Errorf("failed to load project from %s: %w", projectPath, projectErr)
+	}
+
+	// Now we select the tests based on the --run pattern and the test IDs passed as arguments
+	testSelector, testSelectorErr := core.NewTestSelector(runPatternStr, args[1:])
+	if testSelectorErr != nil {
+		return testSelectorErr
+	}
+
+	testFunctions, skippedTestFunctions, testFunctionsErr := listTestFunctions(project, testSelector)
+	if testFunctionsErr != nil {
+		return testFunctionsErr
+	}
+
+	listedTests := []listedTest{}
+	for _, testFunction := range testFunctions {
+		listedTests = append(listedTests, listedTest{
+			Test: testFunction.String(),
+			File: testFunction.TestFile.Path,
+			// Tests skipped using a pragma would be reported as skipped without running
+			Skipped: testFunction.Markers.Skip,
+			Reason:  testFunction.Markers.Reason,
+			line:    testFunction.Line,
+		})
+	}
+
+	for _, skippedTestFunction := range skippedTestFunctions {
+		listedTests = append(listedTests, listedTest{
+			Test:    skippedTestFunction.TestFunction.String(),
+			File:    skippedTestFunction.TestFunction.TestFile.Path,
+			Skipped: true,
+			Reason:  skippedTestFunction.Reason,
+			line:    skippedTestFunction.TestFunction.Line,
+		})
+	}
+
+	sortListedTests(listedTests)
+
+	if jsonOutput {
+		return writeListedTestsJSON(listedTests, cmd.OutOrStdout())
+	}
+
+	return writeListedTestsText(listedTests, cmd.OutOrStdout())
+}
+
+// Puts the skipped tests in place, in the order the tests were discovered
+//
+// Test files are discovered in path order and test functions in the order they are defined in,
+// the parameter sets of a parametrized test function are already in order
+func sortListedTests(listedTests []listedTest) {
+	sort.SliceStable(listedTests, func(i, j int) bool {
+		if listedTests[i].File != listedTests[j].File {
+			return listedTests[i].File < listedTests[j].File
+		}
+
+		return listedTests[i].line < listedTests[j].line
+	})
+}
+
+// Writes one test ID per line, skipped tests are followed by the reason in parentheses
+func writeListedTestsText(listedTests []listedTest, writer io.Writer) error {
+	for _, listed := range listedTests {
+		line := listed.Test
+		if listed.Skipped {
+			line = fmt.Sprintf("%s (skipped: %s)", listed.Test, listed.Reason)
+		}
+
+		_, err := fmt.Fprintln(writer, line)
+		if err != nil {
+			return fmt.Errorf("failed to write test list: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Writes one JSON-encoded listedTest per line
+func writeListedTestsJSON(listedTests []listedTest, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	for _, listed := range listedTests {
+		err := encoder.Encode(listed)
+		if err != nil {
+			return fmt.Errorf("failed to write test list: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteListedTests(t *testing.T) {
+	tests := []struct {
+		name         string
+		listedTests  []listedTest
+		expectedText string
+	}{
+		{
+			name:         "no tests",
+			listedTests:  []listedTest{},
+			expectedText: "",
+		},
+		{
+			name: "selected tests",
+			listedTests: []listedTest{
+				{Test: "test/a_test.star:test_one", File: "test/a_test.star"},
+				{Test: "test/a_test.star:test_params[0]", File: "test/a_test.star"},
+			},
+			expectedText: "test/a_test.star:test_one\ntest/a_test.star:test_params[0]\n",
+		},
+		{
+			name: "skipped tests",
+			listedTests: []listedTest{
+				{Test: "test/a_test.star:test_one", File: "test/a_test.star"},
+				{Test: "test/a_test.star:test_two", File: "test/a_test.star", Skipped: true, Reason: "not selected"},
+				{Test: "test/b_test.star:test_three", File: "test/b_test.star", Skipped: true, Reason: `"quoted" <reason>`},
+			},
+			expectedText: "test/a_test.star:test_one\ntest/a_test.star:test_two (skipped: not selected)\ntest/b_test.star:test_three (skipped: \"quoted\" <reason>)\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text := &bytes.Buffer{}
+			err := writeListedTestsText(test.listedTests, text)
+			if err != nil {
+				t.Fatalf("failed to write text: %v", err)
+			}
+
+			if text.String() != test.expectedText {
+				t.Errorf("expected text %q, got %q", test.expectedText, text.String())
+			}
+
+			jsonLines := &bytes.Buffer{}
+			err = writeListedTestsJSON(test.listedTests, jsonLines)
+			if err != nil {
+				t.Fatalf("failed to write JSON: %v", err)
+			}
+
+			// Every test is written on its own line
+			decoded := []listedTest{}
+			for _, line := range strings.Split(strings.TrimSuffix(jsonLines.String(), "\n"), "\n") {
+				if line == "" {
+					continue
+				}
+
+				listed := listedTest{}
+				err = json.Unmarshal([]byte(line), &listed)
+				if err != nil {
+					t.Fatalf("failed to decode %s: %v", line, err)
+				}
+
+				decoded = append(decoded, listed)
+			}
+
+			if !reflect.DeepEqual(decoded, test.listedTests) {
+				t.Errorf("expected JSON lines to decode to %+v, got %+v", test.listedTests, decoded)
+			}
+		})
+	}
+}
+
+func TestSortListedTests(t *testing.T) {
+	// Selected tests come first and skipped tests after them, file by file
+	listedTests := []listedTest{
+		{Test: "test/a_test.star:test_params[0]", File: "test/a_test.star", line: 5},
+		{Test: "test/a_test.star:test_params[1]", File: "test/a_test.star", line: 5},
+		{Test: "test/b_test.star:test_one", File: "test/b_test.star", line: 1},
+		{Test: "test/a_test.star:test_one", File: "test/a_test.star", Skipped: true, Reason: "not selected", line: 1},
+		{Test: "test/a_test.star:helper", File: "test/a_test.star", Skipped: true, Reason: "does not match test pattern test_*", line: 9},
+		{Test: "test/a/c_test.star:test_one", File: "test/a/c_test.star", Skipped: true, Reason: "not selected", line: 1},
+	}
+
+	sortListedTests(listedTests)
+
+	sorted := []string{}
+	for _, listed := range listedTests {
+		sorted = append(sorted, listed.Test)
+	}
+
+	expected := []string{
+		"test/a/c_test.star:test_one",
+		"test/a_test.star:test_one",
+		"test/a_test.star:test_params[0]",
+		"test/a_test.star:test_params[1]",
+		"test/a_test.star:helper",
+		"test/b_test.star:test_one",
+	}
+	if !reflect.DeepEqual(sorted, expected) {
+		t.Errorf("expected tests in discovery order %q, got %q", expected, sorted)
+	}
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"kurtestosis/cli/core"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	errorsSeparator = "================================================"
+)
+
+// testOutput presents the progress and results of a test run to the user
+type testOutput interface {
+	SuiteStarted(testFile *core.TestFile)
+	TestStarted(testFunction *core.TestFunction)
+	TestFinished(summary *core.TestFunctionSummary)
+	RunFinished(summary *core.TestSuiteSummary)
+	CoverageFinished(summaries []core.CoverageSummary)
+}
+
+func createTestOutput(jsonOutput bool, writer io.Writer) testOutput {
+	if jsonOutput {
+		return &jsonTestOutput{
+			encoder: json.NewEncoder(writer),
+		}
+	}
+
+	return &textTestOutput{}
+}
+
+// textTestOutput logs human-readable test results using logrus
+type textTestOutput struct{}
+
+func (output *textTestOutput) SuiteStarted(testFile *core.TestFile) {
+	logrus.Infof("SUITE %s", testFile)
+}
+
+func (output *textTestOutput) TestStarted(testFunction *core.TestFunction) {
+	logrus.Debugf("\tRUN %s", testFunction)
+}
+
+func (output *textTestOutput) TestFinished(summary *core.TestFunctionSummary) {
+	for _, line := range summary.Output() {
+		logrus.Infof("%s", line)
+	}
+
+	errorsString := strings.Join(summary.ErrorMessages(), "\n\n")
+
+	switch summary.Status() {
+	case core.TestStatusPass:
+		logrus.Infof("\tSUCCESS %s", summary.TestFunction)
+	case core.TestStatusSkip:
+		logrus.Infof("\tSKIP %s%s", summary.TestFunction, formatReason(summary.Reason()))
+	case core.TestStatusXFail:
+		logrus.Infof("\tXFAIL %s%s", summary.TestFunction, formatReason(summary.Reason()))
+		logrus.Debugf("\tExpected failure of %s:\n%s\n%v\n%s", summary.TestFunction, errorsSeparator, errorsString, errorsSeparator)
+	case core.TestStatusXPass:
+		logrus.Warnf("\tXPASS %s%s", summary.TestFunction, formatReason(summary.Reason()))
+	default:
+		logrus.Errorf("\tFAIL %s:\n%s\n%v\n%s", summary.TestFunction, errorsSeparator, errorsString, errorsSeparator)
+	}
+}
+
+func (output *textTestOutput) RunFinished(summary *core.TestSuiteSummary) {
+	counts := summary.Counts()
+
+	logrus.Infof("%d passed, %d failed, %d skipped, %d xfailed, %d xpassed in %s", counts.Passed, counts.Failed, counts.Skipped, counts.XFailed, counts.XPassed, summary.Duration())
+}
+
+func (output *textTestOutput) CoverageFinished(summaries []core.CoverageSummary) {
+	logrus.Info("COVERAGE")
+
+	for _, summary := range summaries {
+		logrus.Infof("\t%s: %d/%d lines (%.1f%%)", summary.File, summary.CoveredLines, summary.Lines, summary.Percentage())
+	}
+}
+
+// Formats the reason for skipping a test or expecting it to fail, if there is one
+func formatReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+
+	return ": " + reason
+}
+
+// jsonTestOutput writes one JSON-encoded core.TestEvent per line
+type jsonTestOutput struct {
+	encoder *json.Encoder
+}
+
+func (output *jsonTestOutput) SuiteStarted(testFile *core.TestFile) {
+	output.emit(core.NewTestFileEvent(core.TestEventActionStart, testFile))
+}
+
+func (output *jsonTestOutput) TestStarted(testFunction *core.TestFunction) {
+	output.emit(core.NewTestFunctionEvent(core.TestEventActionRun, testFunction))
+}
+
+func (output *jsonTestOutput) TestFinished(summary *core.TestFunctionSummary) {
+	for _, line := range summary.Output() {
+		output.emit(core.NewTestOutputEvent(summary.TestFunction, line))
+	}
+
+	output.emit(core.NewTestResultEvent(summary))
+}
+
+func (output *jsonTestOutput) RunFinished(summary *core.TestSuiteSummary) {
+	output.emit(core.NewTestSummaryEvent(summary))
+}
+
+func (output *jsonTestOutput) CoverageFinished(summaries []core.CoverageSummary) {
+	for _, summary := range summaries {
+		output.emit(core.NewCoverageEvent(summary))
+	}
+}
+
+func (output *jsonTestOutput) emit(event *core.TestEvent) {
+	err := output.encoder.Encode(event)
+	if err != nil {
+		logrus.Errorf("Failed to write test event: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"kurtestosis/cli/core"
+)
+
+// The outcome of running a single test function
+type testFunctionResult struct {
+	summary *core.TestFunctionSummary
+	err     error
+}
+
+// Runs test functions in the background using a pool of parallelism workers
+//
+// Every test function gets its own buffered result channel that receives exactly one result
+// once the test function finishes. Closing the stop channel prevents any pending test functions from starting.
+func startTestFunctions(testFunctions []*core.TestFunction, parallelism int, coverage *core.Coverage, stop <-chan struct{}) map[*core.TestFunction]chan testFunctionResult {
+	results := make(map[*core.TestFunction]chan testFunctionResult, len(testFunctions))
+	for _, testFunction := range testFunctions {
+		results[testFunction] = make(chan testFunctionResult, 1)
+	}
+
+	// The queue of test functions waiting to be run
+	pending := make(chan *core.TestFunction)
+	go func() {
+		defer close(pending)
+
+		for _, testFunction := range testFunctions {
+			select {
+			case pending <- testFunction:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < parallelism; i++ {
+		go func() {
+			for testFunction := range pending {
+				summary, err := runTestFunction(testFunction, coverage)
+
+				results[testFunction] <- testFunctionResult{
+					summary: summary,
+					err:     err,
+				}
+			}
+		}()
+	}
+
+	return results
+}
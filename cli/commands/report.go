@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"kurtestosis/cli/core"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	junitReportFormat = "junit"
+)
+
+// Supported report formats along with functions that write them
+var reportWriters = map[string]func(summary *core.TestSuiteSummary, reportPath string) error{
+	junitReportFormat: core.WriteJUnitReport,
+}
+
+// A report requested using the --report CLI flag
+type testReport struct {
+	Format string
+	Path   string
+}
+
+// Parses the values of --report CLI flag in format <format>=<path>
+func parseTestReports(reportStrs []string) ([]testReport, error) {
+	testReports := []testReport{}
+	for _, reportStr := range reportStrs {
+		format, path, found := strings.Cut(reportStr, "=")
+		if !found || path == "" {
+			return nil, fmt.Errorf("invalid %s value %s, expected <format>=<path>", reportStrFlag, reportStr)
+		}
+
+		if _, ok := reportWriters[format]; !ok {
+			return nil, fmt.Errorf("unsupported report format %s in %s", format, reportStr)
+		}
+
+		testReports = append(testReports, testReport{
+			Format: format,
+			Path:   path,
+		})
+	}
+
+	return testReports, nil
+}
+
+func writeTestReports(testReports []testReport, summary *core.TestSuiteSummary) error {
+	for _, report := range testReports {
+		err := reportWriters[report.Format](summary, report.Path)
+		if err != nil {
+			logrus.Errorf("Failed to write %s report to %s: %v", report.Format, report.Path, err)
+
+			return fmt.Errorf("failed to write %s report to %s: %w", report.Format, report.Path, err)
+		}
+
+		logrus.Infof("Wrote %s report to %s", report.Format, report.Path)
+	}
+
+	return nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"kurtestosis/cli/core"
 	"kurtestosis/cli/kurtosis"
 	"kurtestosis/cli/kurtosis/backend"
 
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/kurtosis_core_rpc_api_bindings"
 	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/image_download_mode"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/enclave_structure"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/instructions_plan/resolver"
@@ -24,6 +26,16 @@ const (
 	tempDirRootStrFlag     = "temp-dir"
 	testFilePatternStrFlag = "test-file-pattern"
 	testPatternStrFlag     = "test-pattern"
+	runPatternStrFlag      = "run"
+	reportStrFlag          = "report"
+	jsonOutputFlag         = "json"
+	parallelismFlag        = "parallel"
+	timeoutFlag            = "timeout"
+	maxStepsFlag           = "max-steps"
+	coverageStrFlag        = "coverage"
+	executeFlag            = "execute"
+	updateSnapshotsFlag    = "update-snapshots"
+	offlineFlag            = "offline"
 )
 
 // The variables configurable using CLI flags
@@ -39,12 +51,42 @@ var (
 
 	// Glob pattern to use when looking for test functions
 	testPatternStr string
+
+	// Regular expression to match full test IDs against
+	runPatternStr string
+
+	// Test reports to write after the test run, in <format>=<path> format
+	reportStrs []string
+
+	// Whether to output a stream of JSON test events instead of human-readable logs
+	jsonOutput bool
+
+	// Number of test functions to run concurrently
+	parallelism int
+
+	// Maximum duration of a single test function, 0 means no timeout
+	timeout time.Duration
+
+	// Maximum number of starlark execution steps per starlark thread, 0 means unlimited
+	maxSteps uint64
+
+	// Path to write the lcov coverage report to, coverage is not collected if empty
+	coverageStr string
+
+	// Whether to execute the plan instructions of every test function as they are added
+	execute bool
+
+	// Whether to write the snapshot files instead of comparing against them
+	updateSnapshots bool
+
+	// Whether to forbid fetching remote packages that are not in the temp directory yet
+	offline bool
 )
 
 // RootCmd Suppressing exhaustruct requirement because this struct has ~40 properties
 // nolint: exhaustruct
 var RootCmd = &cobra.Command{
-	Use:   KurtestosisCmdStr,
+	Use:   KurtestosisCmdStr + " <path to kurtosis project> [test IDs...]",
 	Short: "Kurtestosis, Kurtosis test runner CLI",
 	// Cobra will print usage whenever _any_ error occurs, including ones we throw in Kurtosis
 	// This doesn't make sense in 99% of the cases, so just turn them off entirely
@@ -56,7 +98,7 @@ var RootCmd = &cobra.Command{
 	// and will setup things like log level
 	PersistentPreRunE: setupCLI,
 	RunE:              run,
-	Args:              cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Args:              cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 }
 
 func init() {
@@ -67,31 +109,115 @@ func init() {
 		"Sets the level that the CLI will log at ("+strings.Join(core.ToStringList(logrus.AllLevels), "|")+")",
 	)
 
-	RootCmd.Flags().StringVar(
+	RootCmd.PersistentFlags().StringVar(
 		&tempDirRootStr,
 		tempDirRootStrFlag,
 		KurtestosisDefaultTempDirRoot,
 		"Directory for kurtosis temporary files",
 	)
 
-	RootCmd.Flags().StringVar(
+	RootCmd.PersistentFlags().StringVar(
 		&testFilePatternStr,
 		testFilePatternStrFlag,
 		KurtestosisDefaultTestFilePattern,
 		"Glob expression to use when looking for starlark test files",
 	)
 
-	RootCmd.Flags().StringVar(
+	RootCmd.PersistentFlags().StringVar(
 		&testPatternStr,
 		testPatternStrFlag,
 		KurtestosisDefaultTestFunctionPattern,
 		"Glob expression to use when looking for test functions",
 	)
+
+	RootCmd.PersistentFlags().StringVar(
+		&runPatternStr,
+		runPatternStrFlag,
+		"",
+		"Regular expression to match full test IDs (<test file>:<test function>) against. Only matching tests will be run",
+	)
+
+	RootCmd.Flags().StringArrayVar(
+		&reportStrs,
+		reportStrFlag,
+		[]string{},
+		"Writes a test report after the run, in <format>=<path> format (supported formats: "+junitReportFormat+"). Can be specified multiple times",
+	)
+
+	RootCmd.PersistentFlags().BoolVar(
+		&jsonOutput,
+		jsonOutputFlag,
+		false,
+		"Outputs one JSON object (a test event, or a test when listing tests) per line instead of human-readable output. Logs are written to stderr in this mode",
+	)
+
+	RootCmd.Flags().IntVar(
+		&parallelism,
+		parallelismFlag,
+		1,
+		"Number of test functions to run concurrently",
+	)
+
+	RootCmd.Flags().DurationVar(
+		&timeout,
+		timeoutFlag,
+		0,
+		"Maximum duration of a single test function, e.g. 30s. Tests that take longer are stopped and reported as failed (0 means no timeout)",
+	)
+
+	RootCmd.Flags().Uint64Var(
+		&maxSteps,
+		maxStepsFlag,
+		0,
+		"Maximum number of starlark execution steps a test function can take. Tests that take more steps are stopped and reported as failed (0 means unlimited)",
+	)
+
+	RootCmd.Flags().StringVar(
+		&coverageStr,
+		coverageStrFlag,
+		"",
+		"Collects line coverage of the project modules and writes it to this path as an lcov report",
+	)
+
+	RootCmd.Flags().BoolVar(
+		&execute,
+		executeFlag,
+		false,
+		"Executes the plan instructions against an in-memory service network as the tests add them, same as marking every test with the execute pragma",
+	)
+
+	RootCmd.Flags().BoolVar(
+		&updateSnapshots,
+		updateSnapshotsFlag,
+		false,
+		"Writes the snapshots taken by the tests, along with the plan snapshot of every test, instead of comparing against the existing ones",
+	)
+
+	RootCmd.Flags().BoolVar(
+		&offline,
+		offlineFlag,
+		false,
+		"Fails the tests that import remote packages instead of fetching them, unless they have already been fetched into the temp directory",
+	)
 }
 
 func run(cmd *cobra.Command, args []string) error {
 	logrus.Warn("kurtestosis CLI is still work in progress")
 
+	if parallelism < 1 {
+		return fmt.Errorf("invalid %s value %d, at least one test needs to run at a time", parallelismFlag, parallelism)
+	}
+
+	if timeout < 0 {
+		return fmt.Errorf("invalid %s value %s, timeout cannot be negative", timeoutFlag, timeout)
+	}
+
+	// We validate the requested reports before we run anything
+	testReports, testReportsErr := parseTestReports(reportStrs)
+	if testReportsErr != nil {
+		return testReportsErr
+	}
+
 	// First we load the project
 	projectPath := args[0]
 	project, projectErr := core.LoadKurtestosisProject(args[0])
@@ -101,17 +227,33 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load project from %s: %w", projectPath, projectErr)
 	}
 
-	// Let's now get the list of matching test files
-	testFiles, testFilesErr := core.ListMatchingTestFiles(project, testFilePatternStr)
-	if testFilesErr != nil {
-		logrus.Errorf("Error matching test files in project: %v", testFilesErr)
+	// Vendored packages need to match the lockfile, otherwise the test runs would not be reproducible
+	vendorErr := core.VerifyVendorDir(project, project.Lockfile)
+	if vendorErr != nil {
+		logrus.Errorf("Vendor directory of project %s is out of date: %v", projectPath, vendorErr)
 
-		return fmt.Errorf("error matching test files in project: %w", testFilesErr)
+		return fmt.Errorf("vendor directory of project %s is out of date: %w", projectPath, vendorErr)
 	}
 
-	// Exit if there are no test suites to run
-	if len(testFiles) == 0 {
-		logrus.Warn("No test suites found matching the glob pattern")
+	// Now we select the tests to run based on the --run pattern and the test IDs passed as arguments
+	testSelector, testSelectorErr := core.NewTestSelector(runPatternStr, args[1:])
+	if testSelectorErr != nil {
+		return testSelectorErr
+	}
+
+	testFunctions, _, testFunctionsErr := listTestFunctions(project, testSelector)
+	if testFunctionsErr != nil {
+		return testFunctionsErr
+	}
+
+	// Exit if there are no tests to run
+	if len(testFunctions) == 0 {
+		// If the user asked for specific tests, not finding any is an error
+		if testSelector.IsExplicit() {
+			return fmt.Errorf("no tests matched the test selection")
+		}
+
+		logrus.Warn("No tests found")
 
 		return nil
 	}
@@ -119,18 +261,47 @@ func run(cmd *cobra.Command, args []string) error {
 	// The summary of the whole test run
 	testSuiteSummary := core.NewTestSuiteSummary(project)
 
-	// Run the test suites
-	for _, testFile := range testFiles {
-		testFileSummary, err := runTestFile(testFile)
-		if err != nil {
-			logrus.Errorf("Error running test suite %s: %v", testFile, err)
+	// The output will present the test results to the user
+	output := createTestOutput(jsonOutput, cmd.OutOrStdout())
 
-			return fmt.Errorf("error running test suite %s: %v", testFile, err)
-		}
+	// We start running the tests in the background
+	//
+	// The results are collected and presented in order, test file by test file,
+	// so that the output does not depend on the parallelism
+	stopTestFunctions := make(chan struct{})
+	defer close(stopTestFunctions)
+
+	// Coverage is collected across all the test functions
+	coverage, coverageErr := createCoverage(project)
+	if coverageErr != nil {
+		return coverageErr
+	}
+
+	testFunctionResults := startTestFunctions(testFunctions, parallelism, coverage, stopTestFunctions)
+
+	// Collect the results of the test suites
+	for _, testFileFunctions := range groupTestFunctionsByFile(testFunctions) {
+		testFile := testFileFunctions[0].TestFile
+		testFileSummary := collectTestFile(testFile, testFileFunctions, testFunctionResults, output)
 
 		testSuiteSummary.Append(testFileSummary)
 	}
 
+	testSuiteSummary.Finish()
+
+	output.RunFinished(testSuiteSummary)
+
+	// Once all the tests have finished we write the test reports
+	err := writeTestReports(testReports, testSuiteSummary)
+	if err != nil {
+		return err
+	}
+
+	err = writeCoverage(coverage, output)
+	if err != nil {
+		return err
+	}
+
 	if testSuiteSummary.Success() {
 		return nil
 	}
@@ -138,47 +309,48 @@ func run(cmd *cobra.Command, args []string) error {
 	return fmt.Errorf("test suite failed")
 }
 
-func runTestFile(testFile *core.TestFile) (*core.TestFileSummary, error) {
+// Collects the results of the test functions of a test file
+//
+// A test function that could not be run is reported as failed so that the rest of the test run and its reports are not lost
+func collectTestFile(testFile *core.TestFile, testFunctions []*core.TestFunction, testFunctionResults map[*core.TestFunction]chan testFunctionResult, output testOutput) *core.TestFileSummary {
 	// The summary object will hold the test results for this test file
 	testFileSummary := core.NewTestFileSummary(testFile)
 
-	// First we parse the test file and extract the names of matching test functions
-	testFunctions, testFunctionsErr := core.ListMatchingTests(testFile, testPatternStr)
-	if testFunctionsErr != nil {
-		logrus.Errorf("Failed to list matching test functions in %s: %v", testFile, testFunctionsErr)
-
-		return nil, fmt.Errorf("failed to list matching test functions in %s: %w", testFile, testFunctionsErr)
-	}
-
-	// Exit if there are no test suites to run
-	if len(testFunctions) == 0 {
-		logrus.Warnf("No tests found matching the test pattern %s in %s", testPatternStr, testFile)
-
-		return testFileSummary, nil
-	}
+	output.SuiteStarted(testFile)
 
-	logrus.Infof("SUITE %s", testFile)
-
-	// Iterate over the test suites and run them one by one, collecting the test run summaries
+	// Iterate over the test functions and wait for them one by one, collecting the test run summaries
 	for _, testFunction := range testFunctions {
-		testFunctionSummary, testFunctionErr := runTestFunction(testFunction)
+		output.TestStarted(testFunction)
+
+		testFunctionResult := <-testFunctionResults[testFunction]
+		testFunctionSummary, testFunctionErr := testFunctionResult.summary, testFunctionResult.err
 		if testFunctionErr != nil {
 			logrus.Errorf("Failed to run test function %s: %v", testFunction, testFunctionErr)
 
-			return nil, fmt.Errorf("failed to run test function %s: %w", testFunction, testFunctionErr)
+			testFunctionSummary = core.NewErrorTestFunctionSummary(testFunction, fmt.Errorf("failed to run test function: %w", testFunctionErr))
 		}
 
+		output.TestFinished(testFunctionSummary)
+
 		testFileSummary.Append(testFunctionSummary)
 	}
 
-	return testFileSummary, nil
+	return testFileSummary
 }
 
-func runTestFunction(testFunction *core.TestFunction) (*core.TestFunctionSummary, error) {
+func runTestFunction(testFunction *core.TestFunction, coverage *core.Coverage) (*core.TestFunctionSummary, error) {
 	var err error
 
-	// The summary object will hold the test results for this test function
-	logrus.Debugf("\tRUN %ss", testFunction)
+	// We setup a test reporter
+	//
+	// Besides collecting the test output and errors,
+	// a reporter is required for correct functioning of the starlarktest assert module
+	reporter := core.NewTestReporter(testFunction)
+
+	// Tests skipped using a pragma don't need to run at all
+	if reporter.Skipped() {
+		return reporter.Summary(), nil
+	}
 
 	// Let's make a database first
 	enclaveDB, teardownEnclaveDB, err := backend.CreateEnclaveDB()
@@ -187,14 +359,26 @@ func runTestFunction(testFunction *core.TestFunction) (*core.TestFunctionSummary
 	}
 
 	// We want to tear the database down once it's all over
-	defer teardownEnclaveDB()
+	//
+	// A test that could not be stopped in time might still be using the database,
+	// in which case the teardown waits for its interpretation to finish
+	var interpretationFinished <-chan struct{}
+	defer func() {
+		if interpretationFinished == nil {
+			teardownEnclaveDB()
+
+			return
+		}
+
+		teardownAfterInterpretation(interpretationFinished, teardownEnclaveDB)
+	}()
 
 	// Package content providers
-	localGitPackageContentProvider, err := backend.CreateLocalGitPackageContentProvider(tempDirRootStr, enclaveDB)
+	localGitPackageContentProvider, err := backend.CreateLocalGitPackageContentProvider(tempDirRootStr, enclaveDB, offline)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create local git package content provider: %w", err)
 	}
-	localProxyPackageContentProvider := backend.CreateLocalProxyPackageContentProvider(testFunction.TestFile.Project, localGitPackageContentProvider)
+	localProxyPackageContentProvider := backend.CreateLocalProxyPackageContentProvider(testFunction.TestFile.Project, localGitPackageContentProvider, coverage)
 
 	// Now we create the value storage that holds all the starlark values
 	starlarkValueSerde := backend.CreateStarlarkValueSerde()
@@ -203,24 +387,56 @@ func runTestFunction(testFunction *core.TestFunction) (*core.TestFunctionSummary
 		return nil, fmt.Errorf("failed to create kurtosis value stores: %w", err)
 	}
 
+	// The limiter will enforce the step budget and stop the test once it times out
+	limiter := kurtosis.NewThreadLimiter(maxSteps)
+
+	// Interpreters running in parallel need to take turns building the predeclared builtins
+	predeclaredLock := backend.NewPredeclaredLock()
+
+	// Service network (in-memory fake)
+	serviceNetwork := backend.CreateKurtestosisServiceNetwork(predeclaredLock)
+
+	// The test context is cancelled once the test times out
+	ctx, cancel := createTestContext()
+	defer cancel()
+
+	// The test plan collects the plan instructions added by the test and, if requested,
+	// executes them against the service network right away
+	testPlan := backend.NewTestPlan(
+		ctx,
+		execute || testFunction.Markers.Execute,
+		testFunction.TestFile.Project.KurotosisYml.PackageName,
+		serviceNetwork,
+		runtimeValueStore,
+		localProxyPackageContentProvider,
+		testFunction.TestFile.Project.KurotosisYml.PackageReplaceOptions,
+		interpretationTimeValueStore,
+		starlarkValueSerde,
+	)
+
+	// Modules replaced by the test are resolved by a wrapped import_module builtin
+	moduleReplacements := backend.NewModuleReplacements(
+		testFunction.TestFile.Project.KurotosisYml.PackageName,
+		localProxyPackageContentProvider,
+		testFunction.TestFile.Project.KurotosisYml.PackageReplaceOptions,
+	)
+
+	// Snapshots are stored next to the test file
+	snapshots := core.NewSnapshots(testFunction, updateSnapshots)
+
 	// We load all the kurtestosis-specific predeclared starlark builtins
-	predeclared, err := kurtosis.LoadKurtestosisPredeclared(interpretationTimeValueStore)
+	predeclared, err := kurtosis.LoadKurtestosisPredeclared(interpretationTimeValueStore, serviceNetwork, testPlan, moduleReplacements, snapshots, reporter, limiter)
 	if err != nil {
 		return nil, err
 	}
 
-	// And we create a processor function that merges them with kurtosis predeclared builtins
-	processBuiltins := kurtosis.CreateProcessBuiltins(predeclared)
-
-	// We setup a test reporter
-	//
-	// Besides collecting and formatting the test output (mostly TBD),
-	// a reporter is required for correct functioning of the starlarktest assert module
-	reporter := core.NewTestReporter(testFunction)
-	kurtosis.SetupKurtestosisPredeclared(reporter)
+	// Instrumented modules need the coverage builtin
+	if coverage != nil {
+		predeclared = kurtosis.MergeDicts(predeclared, kurtosis.LoadCoveragePredeclared(coverage))
+	}
 
-	// Service network (99% mock)
-	serviceNetwork := backend.CreateKurtestosisServiceNetwork()
+	// And we create a processor function that merges them with kurtosis predeclared builtins
+	processBuiltins := kurtosis.CreateProcessBuiltins(predeclared, moduleReplacements, limiter, predeclaredLock)
 
 	// And finally an interpreter
 	interpreter, err := backend.CreateInterpreter(
@@ -237,40 +453,51 @@ func runTestFunction(testFunction *core.TestFunction) (*core.TestFunctionSummary
 
 	testSuiteScript, mainFunctionName, inputArgs := kurtosis.WrapTestFunction(testFunction)
 
-	_, _, interpretationErr := interpreter.Interpret(
-		context.Background(), // context
-		testFunction.TestFile.Project.KurotosisYml.PackageName, // packageId
-		mainFunctionName, // mainFunctionName
-		testFunction.TestFile.Project.KurotosisYml.PackageReplaceOptions, // packageReplaceOptions
-		startosis_constants.PlaceHolderMainFileForPlaceStandAloneScript,  // relativePathtoMainFile
-		testSuiteScript,                          // serializedStarlark
-		inputArgs,                                // serializedJsonParams
-		false,                                    // nonBlockingMode
-		enclave_structure.NewEnclaveComponents(), // enclaveComponents
-		resolver.NewInstructionsPlanMask(0),      // instructionsPlanMask
-		image_download_mode.ImageDownloadMode_Missing, // imageDownloadMode
-	)
+	interpretationErr, timedOut, interpretationFinished := interpretWithTimeout(ctx, limiter, func(ctx context.Context) *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError {
+		// The interpreter can fail while it holds the predeclared lock
+		defer predeclaredLock.Unlock()
+
+		_, _, interpretationErr := interpreter.Interpret(
+			ctx, // context
+			testFunction.TestFile.Project.KurotosisYml.PackageName, // packageId
+			mainFunctionName, // mainFunctionName
+			testFunction.TestFile.Project.KurotosisYml.PackageReplaceOptions, // packageReplaceOptions
+			startosis_constants.PlaceHolderMainFileForPlaceStandAloneScript,  // relativePathtoMainFile
+			testSuiteScript,                          // serializedStarlark
+			inputArgs,                                // serializedJsonParams
+			false,                                    // nonBlockingMode
+			enclave_structure.NewEnclaveComponents(), // enclaveComponents
+			resolver.NewInstructionsPlanMask(0),      // instructionsPlanMask
+			image_download_mode.ImageDownloadMode_Missing, // imageDownloadMode
+		)
+
+		return interpretationErr
+	})
+
+	// If the test could not be stopped in time, we at least let the user know it timed out
+	//
+	// The interpretation keeps running in the background so the reporter needs to ignore anything it reports from now on
+	if timedOut && interpretationErr == nil {
+		reporter.Abandon(fmt.Sprintf("test timed out after %s and could not be stopped", timeout))
+	}
 
 	// We add any interpretation errors to the summary
+	//
+	// A test function stopped by kurtestosis.skip does not end with an interpretation error, the test runner takes care of that
 	if interpretationErr != nil {
-		reporter.Error(interpretationErr)
+		reporter.Error(interpretationErr.GetErrorMessage())
 	}
 
-	// FIXME The reporter should be doing all the lifting when it comes to logging and formatting
-	// the test output, at the moment it's kinda ready for that but not utitlized at all
+	// The plan is only compared with its snapshot if the test ran to completion, a partial plan would only add noise
+	if !reporter.Skipped() && !reporter.Failed() {
+		err = snapshots.MatchPlan(testPlan.Snapshot())
+		if err != nil {
+			reporter.Error(err.Error())
+		}
+	}
 
 	testFunctionSummary := reporter.Summary()
 
-	if testFunctionSummary.Success() {
-		logrus.Infof("\tSUCCESS %s", testFunction)
-	} else {
-		errorsList := core.ToStringList(testFunctionSummary.Errors())
-		errorsString := strings.ReplaceAll(strings.Join(errorsList, "\n\n"), "\\n", "\n")
-		errorsSeparator := "================================================"
-
-		logrus.Errorf("\tFAIL %s:\n%s\n%v\n%s", testFunction, errorsSeparator, errorsString, errorsSeparator)
-	}
-
 	return testFunctionSummary, nil
 }
 
@@ -285,5 +512,11 @@ func setupCLI(cmd *cobra.Command, args []string) error {
 	logrus.SetOutput(cmd.OutOrStdout())
 	logrus.SetLevel(logLevel)
 
+	// In JSON mode the standard output only contains test events so the logs need to go elsewhere
+	if jsonOutput {
+		logrus.SetOutput(cmd.ErrOrStderr())
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+
 	return nil
 }
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"kurtestosis/cli/core"
+)
+
+func TestCollectTestFile(t *testing.T) {
+	testFile := &core.TestFile{Path: "test/a_test.star"}
+	passingTestFunction := &core.TestFunction{TestFile: testFile, Name: "test_pass"}
+	brokenTestFunction := &core.TestFunction{TestFile: testFile, Name: "test_broken", Markers: core.TestMarkers{XFail: true}}
+	lastTestFunction := &core.TestFunction{TestFile: testFile, Name: "test_last"}
+
+	testFunctionResults := map[*core.TestFunction]chan testFunctionResult{}
+	for _, testFunction := range []*core.TestFunction{passingTestFunction, brokenTestFunction, lastTestFunction} {
+		testFunctionResults[testFunction] = make(chan testFunctionResult, 1)
+	}
+
+	testFunctionResults[passingTestFunction] <- testFunctionResult{summary: core.NewTestReporter(passingTestFunction).Summary()}
+	testFunctionResults[brokenTestFunction] <- testFunctionResult{err: errors.New("failed to create EnclaveDB")}
+	testFunctionResults[lastTestFunction] <- testFunctionResult{summary: core.NewTestReporter(lastTestFunction).Summary()}
+
+	output := createTestOutput(true, &bytes.Buffer{})
+	testFileSummary := collectTestFile(testFile, []*core.TestFunction{passingTestFunction, brokenTestFunction, lastTestFunction}, testFunctionResults, output)
+
+	// A test function that could not be run fails, even if it was expected to fail, and the others are still collected
+	summaries := testFileSummary.Summaries()
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 test function summaries, got %d", len(summaries))
+	}
+
+	expectedStatuses := []core.TestStatus{core.TestStatusPass, core.TestStatusFail, core.TestStatusPass}
+	for i, summary := range summaries {
+		if summary.Status() != expectedStatuses[i] {
+			t.Errorf("expected %s to have status %s, got %s", summary.TestFunction, expectedStatuses[i], summary.Status())
+		}
+	}
+
+	messages := summaries[1].ErrorMessages()
+	if len(messages) != 1 || !strings.Contains(messages[0], "failed to run test function: failed to create EnclaveDB") {
+		t.Errorf("expected the error of the broken test function to be reported, got %q", messages)
+	}
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kurtestosis/cli/kurtosis"
+
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/kurtosis_core_rpc_api_bindings"
+	"github.com/sirupsen/logrus"
+)
+
+// How long to wait for a timed out test to stop before giving up on it
+var timeoutGracePeriod = 5 * time.Second
+
+// Creates a context for a single test function that is cancelled after the --timeout duration
+func createTestContext() (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Runs interpret in the background, stopping all the starlark threads tracked by the limiter once ctx is done
+//
+// A stopped starlark thread returns an error pointing to the location where it was stopped.
+// If the interpretation does not stop within the grace period (e.g. if it's stuck outside of starlark),
+// it is abandoned and only the timedOut flag is returned. The returned channel is closed once interpret returns,
+// anything the abandoned interpretation uses must not be torn down before that
+func interpretWithTimeout(ctx context.Context, limiter *kurtosis.ThreadLimiter, interpret func(ctx context.Context) *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError) (interpretationErr *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError, timedOut bool, finished <-chan struct{}) {
+	interpretationErrs := make(chan *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError, 1)
+	interpretationFinished := make(chan struct{})
+	go func() {
+		defer close(interpretationFinished)
+
+		interpretationErrs <- interpret(ctx)
+	}()
+
+	select {
+	case interpretationErr = <-interpretationErrs:
+		return interpretationErr, false, interpretationFinished
+	case <-ctx.Done():
+	}
+
+	limiter.Cancel(fmt.Sprintf("test timed out after %s", timeout))
+
+	select {
+	case interpretationErr = <-interpretationErrs:
+		// The interpretation might have finished successfully right before the timeout
+		return interpretationErr, interpretationErr != nil, interpretationFinished
+	case <-time.After(timeoutGracePeriod):
+		return nil, true, interpretationFinished
+	}
+}
+
+// Calls teardown once the interpretation has finished
+//
+// An abandoned interpretation can still be running, in which case teardown is called in the background once it finishes
+func teardownAfterInterpretation(interpretationFinished <-chan struct{}, teardown func()) {
+	select {
+	case <-interpretationFinished:
+		teardown()
+	default:
+		logrus.Debugf("Test interpretation is still running, deferring the teardown until it finishes")
+
+		go func() {
+			<-interpretationFinished
+
+			teardown()
+		}()
+	}
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kurtestosis/cli/kurtosis"
+
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/kurtosis_core_rpc_api_bindings"
+	"go.starlark.net/starlark"
+)
+
+func TestInterpretWithTimeout(t *testing.T) {
+	gracePeriod := timeoutGracePeriod
+	timeoutGracePeriod = 50 * time.Millisecond
+	defer func() { timeoutGracePeriod = gracePeriod }()
+
+	t.Run("finished", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		interpretationErr, timedOut, finished := interpretWithTimeout(ctx, kurtosis.NewThreadLimiter(0), func(ctx context.Context) *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError {
+			return &kurtosis_core_rpc_api_bindings.StarlarkInterpretationError{ErrorMessage: "failed"}
+		})
+
+		if timedOut {
+			t.Errorf("expected the interpretation not to time out")
+		}
+
+		if interpretationErr.GetErrorMessage() != "failed" {
+			t.Errorf("expected the interpretation error to be returned, got %v", interpretationErr)
+		}
+
+		assertClosed(t, finished)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		limiter := kurtosis.NewThreadLimiter(0)
+		interpretationErr, timedOut, finished := interpretWithTimeout(ctx, limiter, func(ctx context.Context) *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError {
+			thread := &starlark.Thread{}
+			limiter.Track(thread)
+
+			_, err := starlark.ExecFile(thread, "main.star", "def loop():\n    for i in range(1000000000000):\n        pass\n\nloop()\n", starlark.StringDict{})
+
+			return &kurtosis_core_rpc_api_bindings.StarlarkInterpretationError{ErrorMessage: err.Error()}
+		})
+
+		if !timedOut {
+			t.Errorf("expected the interpretation to time out")
+		}
+
+		if interpretationErr == nil {
+			t.Errorf("expected the error of the stopped interpretation to be returned")
+		}
+
+		assertClosed(t, finished)
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// The interpretation is stuck outside of starlark so the limiter cannot stop it
+		unblock := make(chan struct{})
+		interpretationErr, timedOut, finished := interpretWithTimeout(ctx, kurtosis.NewThreadLimiter(0), func(ctx context.Context) *kurtosis_core_rpc_api_bindings.StarlarkInterpretationError {
+			<-unblock
+
+			return nil
+		})
+
+		if !timedOut {
+			t.Errorf("expected the interpretation to time out")
+		}
+
+		if interpretationErr != nil {
+			t.Errorf("expected no interpretation error, got %v", interpretationErr)
+		}
+
+		select {
+		case <-finished:
+			t.Fatalf("expected the abandoned interpretation to still be running")
+		default:
+		}
+
+		close(unblock)
+		assertClosed(t, finished)
+	})
+}
+
+func TestTeardownAfterInterpretation(t *testing.T) {
+	// A finished interpretation is torn down right away
+	finished := make(chan struct{})
+	close(finished)
+
+	tornDown := false
+	teardownAfterInterpretation(finished, func() { tornDown = true })
+	if !tornDown {
+		t.Errorf("expected the teardown to be called right away")
+	}
+
+	// A running one is torn down once it finishes
+	running := make(chan struct{})
+	tornDownInBackground := make(chan struct{})
+	teardownAfterInterpretation(running, func() { close(tornDownInBackground) })
+
+	select {
+	case <-tornDownInBackground:
+		t.Fatalf("expected the teardown to wait for the interpretation to finish")
+	default:
+	}
+
+	close(running)
+	assertClosed(t, tornDownInBackground)
+}
+
+func assertClosed(t *testing.T, channel <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-channel:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the channel to be closed")
+	}
+}
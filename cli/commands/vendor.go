@@ -0,0 +1,250 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kurtestosis/cli/core"
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/lib/shared_utils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// VendorCmd Suppressing exhaustruct requirement because this struct has ~40 properties
+// nolint: exhaustruct
+var VendorCmd = &cobra.Command{
+	Use:   "vendor <path to kurtosis project>",
+	Short: "Fetches the remote packages imported by a kurtosis project into its vendor directory",
+	Long: "Follows the import_module calls of the project modules and test files, fetches every remote package they import " +
+		"into the " + core.VendorDirName + " directory and pins them to their commits in " + core.LockfileName + ".\n" +
+		"Once vendored, the remote packages are loaded from the " + core.VendorDirName + " directory instead of being fetched when running the tests",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          vendor,
+	Args:          cobra.ExactArgs(1),
+}
+
+// A starlark module whose imports need to be vendored
+type vendorModule struct {
+	Locator string
+	Content string
+}
+
+func init() {
+	RootCmd.AddCommand(VendorCmd)
+}
+
+func vendor(cmd *cobra.Command, args []string) error {
+	// First we load the project
+	projectPath := args[0]
+	project, projectErr := core.LoadKurtestosisProject(projectPath)
+	if projectErr != nil {
+		logrus.Errorf("Failed to load project from %s: %v", projectPath, projectErr)
+
+		return fmt.Errorf("failed to load project from %s: %w", projectPath, projectErr)
+	}
+
+	// The remote packages are fetched the same way they would be when running the tests
+	enclaveDB, teardownEnclaveDB, err := backend.CreateEnclaveDB()
+	if err != nil {
+		return fmt.Errorf("failed to create EnclaveDB: %w", err)
+	}
+	defer teardownEnclaveDB()
+
+	localGitPackageContentProvider, err := backend.CreateLocalGitPackageContentProvider(tempDirRootStr, enclaveDB, false)
+	if err != nil {
+		return fmt.Errorf("failed to create local git package content provider: %w", err)
+	}
+
+	// The import graph starts with all the modules of the project, including the test files
+	modules, err := listProjectModules(project)
+	if err != nil {
+		return err
+	}
+
+	lockedPackages, err := fetchImportedPackages(project, localGitPackageContentProvider, modules)
+	if err != nil {
+		return err
+	}
+
+	previousLockfile := project.Lockfile
+
+	// The vendor directory is recreated from scratch so that packages that are no longer imported are removed
+	err = os.RemoveAll(project.VendorDirPath())
+	if err != nil {
+		return fmt.Errorf("failed to remove vendor directory %s: %w", project.VendorDirPath(), err)
+	}
+
+	lockfile := &core.Lockfile{Packages: []*core.LockedPackage{}}
+	for repositoryPath, lockedPackage := range lockedPackages {
+		clonePath := filepath.Join(localGitPackageContentProvider.RepositoriesDirPath, repositoryPath)
+
+		lockedPackage.Commit, err = getHeadCommit(clonePath)
+		if err != nil {
+			return err
+		}
+
+		vendoredPath := filepath.Join(project.VendorDirPath(), repositoryPath)
+		err = core.CopyPackage(clonePath, vendoredPath)
+		if err != nil {
+			return fmt.Errorf("failed to vendor package %s: %w", lockedPackage.Name, err)
+		}
+
+		lockedPackage.Hash, err = core.HashPackage(vendoredPath)
+		if err != nil {
+			return err
+		}
+
+		logVendoredPackage(lockedPackage, previousLockfile)
+
+		lockfile.Packages = append(lockfile.Packages, lockedPackage)
+	}
+
+	logrus.Infof("Vendored %d packages into %s", len(lockfile.Packages), project.VendorDirPath())
+
+	return core.WriteLockfile(project, lockfile)
+}
+
+// Reads all the starlark modules of a project
+func listProjectModules(project *core.KurtestosisProject) ([]*vendorModule, error) {
+	modulePaths, err := core.ListProjectModules(project)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := []*vendorModule{}
+	for _, modulePath := range modulePaths {
+		content, err := os.ReadFile(filepath.Join(project.Path, modulePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read module %s: %w", modulePath, err)
+		}
+
+		modules = append(modules, &vendorModule{
+			Locator: project.KurotosisYml.PackageName + "/" + filepath.ToSlash(modulePath),
+			Content: string(content),
+		})
+	}
+
+	return modules, nil
+}
+
+// Follows the imports of the modules, fetching every remote module they import along with its own imports
+//
+// Returns the fetched packages by their repository paths
+func fetchImportedPackages(project *core.KurtestosisProject, localGitPackageContentProvider *backend.LocalGitPackageContentProvider, modules []*vendorModule) (map[string]*core.LockedPackage, error) {
+	packageName := project.KurotosisYml.PackageName
+	packageReplaceOptions := project.KurotosisYml.PackageReplaceOptions
+
+	lockedPackages := map[string]*core.LockedPackage{}
+	fetched := map[string]bool{}
+	for len(modules) > 0 {
+		module := modules[0]
+		modules = modules[1:]
+
+		locators, err := core.ListModuleImports(module.Locator, module.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, locator := range locators {
+			absoluteLocator, interpretationErr := localGitPackageContentProvider.GetAbsoluteLocator(packageName, module.Locator, locator, packageReplaceOptions)
+			if interpretationErr != nil {
+				return nil, fmt.Errorf("failed to resolve %s imported by %s: %w", locator, module.Locator, interpretationErr)
+			}
+
+			// The project modules have all been listed already
+			if strings.HasPrefix(absoluteLocator.GetLocator(), packageName) || fetched[absoluteLocator.GetGitURL()] {
+				continue
+			}
+			fetched[absoluteLocator.GetGitURL()] = true
+
+			// Packages replaced with local directories are not vendored, but the packages they import are
+			if localPath, isLocalReplace := project.LocalReplacePath(absoluteLocator.GetLocator()); isLocalReplace {
+				content, err := os.ReadFile(localPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read %s imported by %s from %s: %w", absoluteLocator.GetLocator(), module.Locator, localPath, err)
+				}
+
+				modules = append(modules, &vendorModule{
+					Locator: absoluteLocator.GetLocator(),
+					Content: string(content),
+				})
+
+				continue
+			}
+
+			logrus.Debugf("Fetching %s imported by %s", absoluteLocator.GetGitURL(), module.Locator)
+
+			content, interpretationErr := localGitPackageContentProvider.GetModuleContents(absoluteLocator)
+			if interpretationErr != nil {
+				return nil, fmt.Errorf("failed to fetch %s imported by %s: %w", absoluteLocator.GetGitURL(), module.Locator, interpretationErr)
+			}
+
+			parsedURL, err := shared_utils.ParseGitURL(absoluteLocator.GetGitURL())
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s imported by %s: %w", absoluteLocator.GetGitURL(), module.Locator, err)
+			}
+
+			version := absoluteLocator.GetTagBranchOrCommit()
+			if version == "" {
+				version = parsedURL.GetTagBranchOrCommit()
+			}
+
+			// Only a single version of a package can be cloned at a time
+			if lockedPackage, ok := lockedPackages[parsedURL.GetRelativeRepoPath()]; ok && lockedPackage.Version != version {
+				return nil, fmt.Errorf("package %s is imported at different versions, %q and %q", lockedPackage.Name, lockedPackage.Version, version)
+			}
+
+			lockedPackages[parsedURL.GetRelativeRepoPath()] = &core.LockedPackage{
+				Name:    shared_utils.GithubDomainPrefix + "/" + parsedURL.GetRelativeRepoPath(),
+				Version: version,
+			}
+
+			modules = append(modules, &vendorModule{
+				Locator: absoluteLocator.GetLocator(),
+				Content: content,
+			})
+		}
+	}
+
+	return lockedPackages, nil
+}
+
+// Returns the commit a cloned repository is checked out at
+func getHeadCommit(repositoryPath string) (string, error) {
+	repository, err := git.PlainOpen(repositoryPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository %s: %w", repositoryPath, err)
+	}
+
+	head, err := repository.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the commit of repository %s: %w", repositoryPath, err)
+	}
+
+	return head.Hash().String(), nil
+}
+
+// Lets the user know whether a vendored package is new or has changed since it was last vendored
+func logVendoredPackage(lockedPackage *core.LockedPackage, previousLockfile *core.Lockfile) {
+	for _, previousPackage := range previousLockfile.Packages {
+		if previousPackage.Name != lockedPackage.Name {
+			continue
+		}
+
+		if previousPackage.Commit != lockedPackage.Commit {
+			logrus.Infof("Updated %s from %s to %s", lockedPackage.Name, previousPackage.Commit, lockedPackage.Commit)
+		} else {
+			logrus.Debugf("Vendored %s at %s", lockedPackage.Name, lockedPackage.Commit)
+		}
+
+		return
+	}
+
+	logrus.Infof("Added %s at %s", lockedPackage.Name, lockedPackage.Commit)
+}
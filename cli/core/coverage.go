@@ -0,0 +1,204 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Coverage collects the lines of the package under test executed across all the test functions
+//
+// Since test functions can run in parallel, all the methods are safe for concurrent use
+type Coverage struct {
+	Project *KurtestosisProject
+	// Paths of the files that should not be instrumented, relative to the project root
+	excludedFiles map[string]bool
+
+	mutex sync.Mutex
+	// Number of hits for every instrumented line, keyed by file path relative to the project root
+	files map[string]map[int]int
+}
+
+func NewCoverage(project *KurtestosisProject, testFiles []*TestFile) *Coverage {
+	// Test files are loaded through the same package content provider
+	// but we are only interested in the coverage of the package under test
+	excludedFiles := map[string]bool{}
+	for _, testFile := range testFiles {
+		excludedFiles[testFile.Path] = true
+	}
+
+	return &Coverage{
+		Project:       project,
+		excludedFiles: excludedFiles,
+		files:         map[string]map[int]int{},
+	}
+}
+
+// Includes returns true if the file (relative to the project root) should be instrumented
+func (coverage *Coverage) Includes(file string) bool {
+	return !coverage.excludedFiles[file]
+}
+
+// Register records the instrumented lines of a file so that the lines that never executed are reported too
+func (coverage *Coverage) Register(file string, lines []int) {
+	coverage.mutex.Lock()
+	defer coverage.mutex.Unlock()
+
+	fileLines, ok := coverage.files[file]
+	if !ok {
+		fileLines = map[int]int{}
+		coverage.files[file] = fileLines
+	}
+
+	for _, line := range lines {
+		if _, ok := fileLines[line]; !ok {
+			fileLines[line] = 0
+		}
+	}
+}
+
+// RegisterProjectModules registers the instrumented lines of all the project modules
+//
+// Modules that are never loaded by any test would otherwise be missing from the report, overstating the coverage.
+// Modules that cannot be parsed are skipped since they can only fail the tests that load them
+func (coverage *Coverage) RegisterProjectModules() error {
+	modulePaths, err := ListProjectModules(coverage.Project)
+	if err != nil {
+		return err
+	}
+
+	for _, modulePath := range modulePaths {
+		if !coverage.Includes(modulePath) {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(coverage.Project.Path, modulePath))
+		if err != nil {
+			return fmt.Errorf("failed to read module %s: %w", modulePath, err)
+		}
+
+		_, lines, err := InstrumentModule(modulePath, string(content))
+		if err != nil {
+			logrus.Warnf("Failed to instrument module %s for coverage: %v", modulePath, err)
+
+			continue
+		}
+
+		coverage.Register(modulePath, lines)
+	}
+
+	return nil
+}
+
+// Hit records an execution of a line
+func (coverage *Coverage) Hit(file string, line int) {
+	coverage.mutex.Lock()
+	defer coverage.mutex.Unlock()
+
+	fileLines, ok := coverage.files[file]
+	if !ok {
+		fileLines = map[int]int{}
+		coverage.files[file] = fileLines
+	}
+
+	fileLines[line]++
+}
+
+// CoverageSummary holds the line coverage of a single file
+type CoverageSummary struct {
+	File         string
+	Lines        int
+	CoveredLines int
+}
+
+func (summary CoverageSummary) Percentage() float64 {
+	if summary.Lines == 0 {
+		return 100
+	}
+
+	return 100 * float64(summary.CoveredLines) / float64(summary.Lines)
+}
+
+// Summaries returns the line coverage of every instrumented file, ordered by file path
+func (coverage *Coverage) Summaries() []CoverageSummary {
+	coverage.mutex.Lock()
+	defer coverage.mutex.Unlock()
+
+	summaries := []CoverageSummary{}
+	for _, file := range coverage.sortedFiles() {
+		summary := CoverageSummary{
+			File:  file,
+			Lines: len(coverage.files[file]),
+		}
+
+		for _, hits := range coverage.files[file] {
+			if hits > 0 {
+				summary.CoveredLines++
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+// WriteLcovReport writes the collected coverage to reportPath in lcov tracefile format
+func (coverage *Coverage) WriteLcovReport(reportPath string) error {
+	coverage.mutex.Lock()
+	defer coverage.mutex.Unlock()
+
+	var report strings.Builder
+	for _, file := range coverage.sortedFiles() {
+		fileLines := coverage.files[file]
+
+		lines := []int{}
+		for line := range fileLines {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		report.WriteString("TN:\n")
+		report.WriteString(fmt.Sprintf("SF:%s\n", filepath.Join(coverage.Project.Path, file)))
+
+		coveredLines := 0
+		for _, line := range lines {
+			report.WriteString(fmt.Sprintf("DA:%d,%d\n", line, fileLines[line]))
+
+			if fileLines[line] > 0 {
+				coveredLines++
+			}
+		}
+
+		report.WriteString(fmt.Sprintf("LF:%d\n", len(lines)))
+		report.WriteString(fmt.Sprintf("LH:%d\n", coveredLines))
+		report.WriteString("end_of_record\n")
+	}
+
+	err := os.MkdirAll(filepath.Dir(reportPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for coverage report %s: %w", reportPath, err)
+	}
+
+	err = os.WriteFile(reportPath, []byte(report.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write coverage report to %s: %w", reportPath, err)
+	}
+
+	return nil
+}
+
+func (coverage *Coverage) sortedFiles() []string {
+	files := []string{}
+	for file := range coverage.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteLcovReport(t *testing.T) {
+	projectPath := t.TempDir()
+
+	tests := []struct {
+		name     string
+		record   func(coverage *Coverage)
+		expected string
+	}{
+		{
+			name:     "no files",
+			record:   func(coverage *Coverage) {},
+			expected: "",
+		},
+		{
+			name: "registered file that never ran",
+			record: func(coverage *Coverage) {
+				coverage.Register("main.star", []int{3, 1, 2})
+			},
+			expected: "TN:\nSF:" + filepath.Join(projectPath, "main.star") + "\nDA:1,0\nDA:2,0\nDA:3,0\nLF:3\nLH:0\nend_of_record\n",
+		},
+		{
+			name: "hits",
+			record: func(coverage *Coverage) {
+				coverage.Register("main.star", []int{1, 2, 3})
+				coverage.Hit("main.star", 1)
+				coverage.Hit("main.star", 1)
+				coverage.Hit("main.star", 3)
+			},
+			expected: "TN:\nSF:" + filepath.Join(projectPath, "main.star") + "\nDA:1,2\nDA:2,0\nDA:3,1\nLF:3\nLH:2\nend_of_record\n",
+		},
+		{
+			name: "registering again keeps the hits",
+			record: func(coverage *Coverage) {
+				coverage.Register("main.star", []int{1})
+				coverage.Hit("main.star", 1)
+				coverage.Register("main.star", []int{1, 2})
+			},
+			expected: "TN:\nSF:" + filepath.Join(projectPath, "main.star") + "\nDA:1,1\nDA:2,0\nLF:2\nLH:1\nend_of_record\n",
+		},
+		{
+			name: "files sorted by path",
+			record: func(coverage *Coverage) {
+				coverage.Register("src/b.star", []int{1})
+				coverage.Register("a.star", []int{1})
+				coverage.Hit("src/b.star", 1)
+			},
+			expected: "TN:\nSF:" + filepath.Join(projectPath, "a.star") + "\nDA:1,0\nLF:1\nLH:0\nend_of_record\n" +
+				"TN:\nSF:" + filepath.Join(projectPath, "src/b.star") + "\nDA:1,1\nLF:1\nLH:1\nend_of_record\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			coverage := NewCoverage(&KurtestosisProject{Path: projectPath}, []*TestFile{})
+			test.record(coverage)
+
+			reportPath := filepath.Join(t.TempDir(), "coverage", "lcov.info")
+			err := coverage.WriteLcovReport(reportPath)
+			if err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+
+			report, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+
+			if string(report) != test.expected {
+				t.Errorf("expected report:\n%s\ngot:\n%s", test.expected, report)
+			}
+		})
+	}
+}
+
+func TestCoverageSummaries(t *testing.T) {
+	coverage := NewCoverage(&KurtestosisProject{Path: t.TempDir()}, []*TestFile{})
+	coverage.Register("main.star", []int{1, 2, 3, 4})
+	coverage.Hit("main.star", 1)
+	coverage.Hit("main.star", 4)
+	coverage.Register("empty.star", []int{})
+
+	expected := []CoverageSummary{
+		{File: "empty.star", Lines: 0, CoveredLines: 0},
+		{File: "main.star", Lines: 4, CoveredLines: 2},
+	}
+
+	summaries := coverage.Summaries()
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Fatalf("expected summaries %+v, got %+v", expected, summaries)
+	}
+
+	for i, expectedPercentage := range []float64{100, 50} {
+		if summaries[i].Percentage() != expectedPercentage {
+			t.Errorf("expected %s to be %.1f%% covered, got %.1f%%", summaries[i].File, expectedPercentage, summaries[i].Percentage())
+		}
+	}
+}
+
+func TestRegisterProjectModules(t *testing.T) {
+	projectPath := t.TempDir()
+	writeTestFile(t, filepath.Join(projectPath, "main.star"), "def run(plan):\n    return 1\n")
+	writeTestFile(t, filepath.Join(projectPath, "lib/helpers.star"), "a = 1\nb = 2\n")
+	writeTestFile(t, filepath.Join(projectPath, "lib/broken.star"), "def broken(:\n")
+	writeTestFile(t, filepath.Join(projectPath, "main_test.star"), "def test_run(plan):\n    pass\n")
+	writeTestFile(t, filepath.Join(projectPath, VendorDirName, "org/repo/main.star"), "a = 1\n")
+	writeTestFile(t, filepath.Join(projectPath, ".hidden/main.star"), "a = 1\n")
+	writeTestFile(t, filepath.Join(projectPath, "README.md"), "# readme\n")
+
+	project := &KurtestosisProject{Path: projectPath}
+	coverage := NewCoverage(project, []*TestFile{{Project: project, Path: "main_test.star"}})
+
+	err := coverage.RegisterProjectModules()
+	if err != nil {
+		t.Fatalf("failed to register project modules: %v", err)
+	}
+
+	// Test files, vendored and hidden modules and modules that cannot be parsed are left out
+	expected := []CoverageSummary{
+		{File: filepath.Join("lib", "helpers.star"), Lines: 2, CoveredLines: 0},
+		{File: "main.star", Lines: 1, CoveredLines: 0},
+	}
+
+	summaries := coverage.Summaries()
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("expected summaries %+v, got %+v", expected, summaries)
+	}
+}
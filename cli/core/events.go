@@ -0,0 +1,106 @@
+package core
+
+import (
+	"time"
+)
+
+type TestEventAction string
+
+const (
+	// A test file is about to be run
+	TestEventActionStart TestEventAction = "start"
+	// A test function is about to be run
+	TestEventActionRun TestEventAction = "run"
+	// A test function printed output
+	TestEventActionOutput TestEventAction = "output"
+	// A test function passed
+	TestEventActionPass TestEventAction = "pass"
+	// A test function failed
+	TestEventActionFail TestEventAction = "fail"
+	// A test function was skipped
+	TestEventActionSkip TestEventAction = "skip"
+	// A test function failed as expected
+	TestEventActionXFail TestEventAction = "xfail"
+	// A test function passed even though it was expected to fail
+	TestEventActionXPass TestEventAction = "xpass"
+	// The whole test run finished
+	TestEventActionSummary TestEventAction = "summary"
+	// Line coverage of a single file
+	TestEventActionCoverage TestEventAction = "coverage"
+)
+
+// TestEvent is a machine-readable representation of a single test run event,
+// modeled after the output of go test -json
+type TestEvent struct {
+	Time     time.Time
+	Action   TestEventAction
+	File     string           `json:",omitempty"`
+	Test     string           `json:",omitempty"`
+	Elapsed  float64          `json:",omitempty"`
+	Output   string           `json:",omitempty"`
+	Reason   string           `json:",omitempty"`
+	Errors   []string         `json:",omitempty"`
+	Counts   *TestCounts      `json:",omitempty"`
+	Coverage *CoverageSummary `json:",omitempty"`
+}
+
+func NewTestFileEvent(action TestEventAction, testFile *TestFile) *TestEvent {
+	return &TestEvent{
+		Time:   time.Now(),
+		Action: action,
+		File:   testFile.Path,
+	}
+}
+
+func NewTestFunctionEvent(action TestEventAction, testFunction *TestFunction) *TestEvent {
+	return &TestEvent{
+		Time:   time.Now(),
+		Action: action,
+		File:   testFunction.TestFile.Path,
+		Test:   testFunction.String(),
+	}
+}
+
+func NewTestOutputEvent(testFunction *TestFunction, output string) *TestEvent {
+	event := NewTestFunctionEvent(TestEventActionOutput, testFunction)
+	event.Output = output
+
+	return event
+}
+
+var testResultEventActions = map[TestStatus]TestEventAction{
+	TestStatusPass:  TestEventActionPass,
+	TestStatusFail:  TestEventActionFail,
+	TestStatusSkip:  TestEventActionSkip,
+	TestStatusXFail: TestEventActionXFail,
+	TestStatusXPass: TestEventActionXPass,
+}
+
+func NewTestResultEvent(summary *TestFunctionSummary) *TestEvent {
+	event := NewTestFunctionEvent(testResultEventActions[summary.Status()], summary.TestFunction)
+	event.Elapsed = summary.Duration().Seconds()
+	event.Errors = summary.ErrorMessages()
+	event.Reason = summary.Reason()
+
+	return event
+}
+
+func NewTestSummaryEvent(summary *TestSuiteSummary) *TestEvent {
+	counts := summary.Counts()
+
+	return &TestEvent{
+		Time:    time.Now(),
+		Action:  TestEventActionSummary,
+		Elapsed: summary.Duration().Seconds(),
+		Counts:  &counts,
+	}
+}
+
+func NewCoverageEvent(summary CoverageSummary) *TestEvent {
+	return &TestEvent{
+		Time:     time.Now(),
+		Action:   TestEventActionCoverage,
+		File:     summary.File,
+		Coverage: &summary,
+	}
+}
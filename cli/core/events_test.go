@@ -0,0 +1,157 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewTestResultEvent(t *testing.T) {
+	testFile := &TestFile{Path: "test/events_test.star"}
+
+	tests := []struct {
+		name           string
+		testFunction   *TestFunction
+		report         func(reporter *TestReporter)
+		expectedAction TestEventAction
+		expectedTest   string
+		expectedReason string
+		expectedErrors []string
+	}{
+		{
+			name:           "pass",
+			testFunction:   &TestFunction{TestFile: testFile, Name: "test_pass"},
+			report:         func(reporter *TestReporter) {},
+			expectedAction: TestEventActionPass,
+			expectedTest:   "test/events_test.star:test_pass",
+		},
+		{
+			name:           "fail",
+			testFunction:   &TestFunction{TestFile: testFile, Name: "test_fail"},
+			report:         func(reporter *TestReporter) { reporter.Error("oh no") },
+			expectedAction: TestEventActionFail,
+			expectedTest:   "test/events_test.star:test_fail",
+			expectedErrors: []string{"oh no"},
+		},
+		{
+			name:           "skip",
+			testFunction:   &TestFunction{TestFile: testFile, Name: "test_skip"},
+			report:         func(reporter *TestReporter) { reporter.Skip("not yet") },
+			expectedAction: TestEventActionSkip,
+			expectedTest:   "test/events_test.star:test_skip",
+			expectedReason: "not yet",
+		},
+		{
+			name:         "xfail",
+			testFunction: &TestFunction{TestFile: testFile, Name: "test_xfail"},
+			report: func(reporter *TestReporter) {
+				reporter.ExpectFailure("known bug")
+				reporter.Error("oh no")
+			},
+			expectedAction: TestEventActionXFail,
+			expectedTest:   "test/events_test.star:test_xfail",
+			expectedReason: "known bug",
+			expectedErrors: []string{"oh no"},
+		},
+		{
+			name:           "xpass",
+			testFunction:   &TestFunction{TestFile: testFile, Name: "test_xpass"},
+			report:         func(reporter *TestReporter) { reporter.ExpectFailure("known bug") },
+			expectedAction: TestEventActionXPass,
+			expectedTest:   "test/events_test.star:test_xpass",
+			expectedReason: "known bug",
+		},
+		{
+			name:           "parametrized",
+			testFunction:   &TestFunction{TestFile: testFile, Name: "test_params", Parametrized: true, ParamsIndex: 2},
+			report:         func(reporter *TestReporter) {},
+			expectedAction: TestEventActionPass,
+			expectedTest:   "test/events_test.star:test_params[2]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reporter := NewTestReporter(test.testFunction)
+			test.report(reporter)
+
+			event := NewTestResultEvent(reporter.Summary())
+			if event.Action != test.expectedAction {
+				t.Errorf("expected action %q, got %q", test.expectedAction, event.Action)
+			}
+			if event.File != testFile.Path {
+				t.Errorf("expected file %q, got %q", testFile.Path, event.File)
+			}
+			if event.Test != test.expectedTest {
+				t.Errorf("expected test %q, got %q", test.expectedTest, event.Test)
+			}
+			if event.Reason != test.expectedReason {
+				t.Errorf("expected reason %q, got %q", test.expectedReason, event.Reason)
+			}
+			if len(event.Errors) != 0 || len(test.expectedErrors) != 0 {
+				if !reflect.DeepEqual(event.Errors, test.expectedErrors) {
+					t.Errorf("expected errors %q, got %q", test.expectedErrors, event.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestTestEventJSONEscaping(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{name: "quotes", message: `expected "a" but got 'b'`},
+		{name: "backslashes", message: `C:\path\to\file`},
+		{name: "newlines", message: "first line\nsecond line\r\n\tindented line"},
+		{name: "markup", message: `<service name="a"> & </service>`},
+		{name: "control characters", message: "bell \a and null \x00"},
+		{name: "unicode", message: "ünïcödé ✓ \u2028"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testFunction := &TestFunction{TestFile: &TestFile{Path: "test/events_test.star"}, Name: "test_escaping"}
+
+			reporter := NewTestReporter(testFunction)
+			reporter.Log(test.message)
+			reporter.Error(test.message)
+
+			// Events are written one per line the same way the JSON output does
+			buffer := &bytes.Buffer{}
+			encoder := json.NewEncoder(buffer)
+			for _, event := range []*TestEvent{NewTestOutputEvent(testFunction, test.message), NewTestResultEvent(reporter.Summary())} {
+				err := encoder.Encode(event)
+				if err != nil {
+					t.Fatalf("failed to encode event: %v", err)
+				}
+			}
+
+			lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buffer.String())
+			}
+
+			outputEvent := TestEvent{}
+			err := json.Unmarshal([]byte(lines[0]), &outputEvent)
+			if err != nil {
+				t.Fatalf("failed to decode output event %s: %v", lines[0], err)
+			}
+			if outputEvent.Output != test.message {
+				t.Errorf("expected output %q, got %q", test.message, outputEvent.Output)
+			}
+
+			resultEvent := TestEvent{}
+			err = json.Unmarshal([]byte(lines[1]), &resultEvent)
+			if err != nil {
+				t.Fatalf("failed to decode result event %s: %v", lines[1], err)
+			}
+			if len(resultEvent.Errors) != 1 || resultEvent.Errors[0] != test.message {
+				t.Errorf("expected errors %q, got %q", []string{test.message}, resultEvent.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"fmt"
+
+	"go.starlark.net/syntax"
+)
+
+// Name of the builtin kurtosis uses to import modules
+const importModuleBuiltinName = "import_module"
+
+// Name of the import_module argument that holds the module locator
+const importModuleFileArgName = "module_file"
+
+// ListModuleImports finds the locators of all the modules imported by a starlark module
+//
+// Only the import_module calls with a string literal locator are found, locators built at runtime cannot be known without
+// interpreting the module
+func ListModuleImports(filename string, content string) ([]string, error) {
+	parsed, err := syntax.Parse(filename, content, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	locators := []string{}
+	syntax.Walk(parsed, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok {
+			return true
+		}
+
+		fn, ok := call.Fn.(*syntax.Ident)
+		if !ok || fn.Name != importModuleBuiltinName {
+			return true
+		}
+
+		for index, arg := range call.Args {
+			// The locator can be passed either as the first positional argument or by name
+			if binary, ok := arg.(*syntax.BinaryExpr); ok && binary.Op == syntax.EQ {
+				if name, ok := binary.X.(*syntax.Ident); ok && name.Name == importModuleFileArgName {
+					arg = binary.Y
+				} else {
+					continue
+				}
+			} else if index > 0 {
+				continue
+			}
+
+			if literal, ok := arg.(*syntax.Literal); ok && literal.Token == syntax.STRING {
+				locators = append(locators, literal.Value.(string))
+			}
+		}
+
+		return true
+	})
+
+	return locators, nil
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListModuleImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "no imports",
+			content:  "def run(plan):\n    return 1\n",
+			expected: []string{},
+		},
+		{
+			name:     "positional locator",
+			content:  "lib = import_module(\"github.com/org/repo/lib.star\")\n",
+			expected: []string{"github.com/org/repo/lib.star"},
+		},
+		{
+			name:     "named locator",
+			content:  "lib = import_module(module_file = \"github.com/org/repo/lib.star\")\n",
+			expected: []string{"github.com/org/repo/lib.star"},
+		},
+		{
+			name:     "versioned and relative locators",
+			content:  "a = import_module(\"github.com/org/repo/a.star@v1.2\")\nb = import_module(\"./b.star\")\nc = import_module(\"/c.star\")\n",
+			expected: []string{"github.com/org/repo/a.star@v1.2", "./b.star", "/c.star"},
+		},
+		{
+			name:     "imports inside functions",
+			content:  "def run(plan):\n    lib = import_module(\"github.com/org/repo/lib.star\")\n    return lib.run(plan)\n",
+			expected: []string{"github.com/org/repo/lib.star"},
+		},
+		{
+			name:     "locator built at runtime",
+			content:  "name = \"lib\"\nlib = import_module(\"github.com/org/repo/\" + name + \".star\")\nother = import_module(LOCATOR)\n",
+			expected: []string{},
+		},
+		{
+			name:     "other calls and arguments",
+			content:  "x = read_file(\"github.com/org/repo/file.txt\")\ny = import_module(LOCATOR, \"github.com/org/repo/ignored.star\")\nz = other.import_module(\"github.com/org/repo/method.star\")\n",
+			expected: []string{},
+		},
+		{
+			name:     "other named arguments",
+			content:  "lib = import_module(other = \"github.com/org/repo/ignored.star\")\n",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			locators, err := ListModuleImports("main.star", test.content)
+			if err != nil {
+				t.Fatalf("failed to list imports: %v", err)
+			}
+
+			if !reflect.DeepEqual(locators, test.expected) {
+				t.Errorf("expected locators %q, got %q", test.expected, locators)
+			}
+		})
+	}
+}
+
+func TestListModuleImportsSyntaxError(t *testing.T) {
+	_, err := ListModuleImports("main.star", "lib = import_module(\n")
+	if err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
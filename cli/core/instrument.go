@@ -0,0 +1,141 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/syntax"
+)
+
+// Name of the builtin that records line coverage
+//
+// It's called as __cover__(file, line) before every simple statement
+// and as __cover__(file, line, value) around the conditions of if, elif, for and while statements,
+// in which case it returns value
+const CoverageBuiltinName = "__cover__"
+
+// A piece of code to be inserted at a position in the source
+type insertion struct {
+	line int
+	col  int
+	text string
+}
+
+// InstrumentModule adds coverage calls to the source of a starlark module
+//
+// All the calls are inserted on the same lines as the original statements
+// so the line numbers in stack traces are not affected. The instrumented lines are returned along with the source
+func InstrumentModule(file string, src string) (string, []int, error) {
+	parsed, err := syntax.Parse(file, src, 0)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	quotedFile := strconv.Quote(file)
+	insertions := []insertion{}
+	lines := []int{}
+
+	// Every simple statement gets a coverage call inserted before it
+	coverStmt := func(start syntax.Position) {
+		insertions = append(insertions, insertion{
+			line: int(start.Line),
+			col:  int(start.Col),
+			text: fmt.Sprintf("%s(%s, %d); ", CoverageBuiltinName, quotedFile, start.Line),
+		})
+		lines = append(lines, int(start.Line))
+	}
+
+	// Compound statements cannot follow a semicolon so we wrap their conditions instead
+	coverExpr := func(line syntax.Position, expr syntax.Expr) {
+		start, end := expr.Span()
+		insertions = append(insertions, insertion{
+			line: int(start.Line),
+			col:  int(start.Col),
+			text: fmt.Sprintf("%s(%s, %d, ", CoverageBuiltinName, quotedFile, line.Line),
+		}, insertion{
+			line: int(end.Line),
+			col:  int(end.Col),
+			text: ")",
+		})
+		lines = append(lines, int(line.Line))
+	}
+
+	var coverStmts func(stmts []syntax.Stmt)
+	coverStmts = func(stmts []syntax.Stmt) {
+		for _, stmt := range stmts {
+			switch stmt := stmt.(type) {
+			case *syntax.DefStmt:
+				coverStmts(stmt.Body)
+			case *syntax.IfStmt:
+				coverExpr(stmt.If, stmt.Cond)
+				coverStmts(stmt.True)
+				coverStmts(stmt.False)
+			case *syntax.ForStmt:
+				coverExpr(stmt.For, stmt.X)
+				coverStmts(stmt.Body)
+			case *syntax.WhileStmt:
+				coverExpr(stmt.While, stmt.Cond)
+				coverStmts(stmt.Body)
+			case *syntax.LoadStmt:
+				// Load statements are resolved before the module executes, there is nothing to record
+			default:
+				start, _ := stmt.Span()
+				coverStmt(start)
+			}
+		}
+	}
+	coverStmts(parsed.Stmts)
+
+	return applyInsertions(src, insertions), uniqueSortedLines(lines), nil
+}
+
+// Inserts code into the source, starting from the end so that the positions remain valid
+func applyInsertions(src string, insertions []insertion) string {
+	sourceLines := strings.SplitAfter(src, "\n")
+
+	sort.SliceStable(insertions, func(i, j int) bool {
+		if insertions[i].line != insertions[j].line {
+			return insertions[i].line > insertions[j].line
+		}
+
+		return insertions[i].col > insertions[j].col
+	})
+
+	for _, insertion := range insertions {
+		sourceLine := sourceLines[insertion.line-1]
+		offset := runeColumnOffset(sourceLine, insertion.col)
+
+		sourceLines[insertion.line-1] = sourceLine[:offset] + insertion.text + sourceLine[offset:]
+	}
+
+	return strings.Join(sourceLines, "")
+}
+
+// Converts a 1-based rune column into a byte offset within a line
+func runeColumnOffset(line string, col int) int {
+	column := 1
+	for offset := range line {
+		if column == col {
+			return offset
+		}
+
+		column++
+	}
+
+	return len(line)
+}
+
+func uniqueSortedLines(lines []int) []int {
+	sort.Ints(lines)
+
+	unique := []int{}
+	for i, line := range lines {
+		if i == 0 || lines[i-1] != line {
+			unique = append(unique, line)
+		}
+	}
+
+	return unique
+}
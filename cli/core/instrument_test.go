@@ -0,0 +1,120 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"go.starlark.net/syntax"
+)
+
+func TestInstrumentModule(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedSrc   string
+		expectedLines []int
+	}{
+		{
+			name:          "empty module",
+			src:           "",
+			expectedSrc:   "",
+			expectedLines: []int{},
+		},
+		{
+			name:          "simple statements",
+			src:           "a = 1\nprint(a)\n",
+			expectedSrc:   "__cover__(\"main.star\", 1); a = 1\n__cover__(\"main.star\", 2); print(a)\n",
+			expectedLines: []int{1, 2},
+		},
+		{
+			name:          "function body",
+			src:           "def run(plan):\n    x = 1\n    return x\n",
+			expectedSrc:   "def run(plan):\n    __cover__(\"main.star\", 2); x = 1\n    __cover__(\"main.star\", 3); return x\n",
+			expectedLines: []int{2, 3},
+		},
+		{
+			name:          "if, elif and else",
+			src:           "if a:\n    b()\nelif c:\n    d()\nelse:\n    e()\n",
+			expectedSrc:   "if __cover__(\"main.star\", 1, a):\n    __cover__(\"main.star\", 2); b()\nelif __cover__(\"main.star\", 3, c):\n    __cover__(\"main.star\", 4); d()\nelse:\n    __cover__(\"main.star\", 6); e()\n",
+			expectedLines: []int{1, 2, 3, 4, 6},
+		},
+		{
+			name:          "for loop",
+			src:           "for x in [1, 2]:\n    f(x)\n",
+			expectedSrc:   "for x in __cover__(\"main.star\", 1, [1, 2]):\n    __cover__(\"main.star\", 2); f(x)\n",
+			expectedLines: []int{1, 2},
+		},
+		{
+			name:          "multiline statement",
+			src:           "x = f(\n    1,\n    2,\n)\n",
+			expectedSrc:   "__cover__(\"main.star\", 1); x = f(\n    1,\n    2,\n)\n",
+			expectedLines: []int{1},
+		},
+		{
+			name:          "multiple statements on a line",
+			src:           "a = 1; b = 2\n",
+			expectedSrc:   "__cover__(\"main.star\", 1); a = 1; __cover__(\"main.star\", 1); b = 2\n",
+			expectedLines: []int{1},
+		},
+		{
+			name:          "unicode before the statement",
+			src:           "s = \"ü\"; t = s\n",
+			expectedSrc:   "__cover__(\"main.star\", 1); s = \"ü\"; __cover__(\"main.star\", 1); t = s\n",
+			expectedLines: []int{1},
+		},
+		{
+			name:          "load statement",
+			src:           "load(\"other.star\", \"x\")\ny = x\n",
+			expectedSrc:   "load(\"other.star\", \"x\")\n__cover__(\"main.star\", 2); y = x\n",
+			expectedLines: []int{2},
+		},
+		{
+			name:          "no trailing newline",
+			src:           "a = 1",
+			expectedSrc:   "__cover__(\"main.star\", 1); a = 1",
+			expectedLines: []int{1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instrumentedSrc, lines, err := InstrumentModule("main.star", test.src)
+			if err != nil {
+				t.Fatalf("failed to instrument module: %v", err)
+			}
+
+			if instrumentedSrc != test.expectedSrc {
+				t.Errorf("expected instrumented source:\n%s\ngot:\n%s", test.expectedSrc, instrumentedSrc)
+			}
+
+			if !reflect.DeepEqual(lines, test.expectedLines) {
+				t.Errorf("expected lines %v, got %v", test.expectedLines, lines)
+			}
+
+			// The instrumented source needs to stay valid starlark
+			_, err = syntax.Parse("main.star", instrumentedSrc, 0)
+			if err != nil {
+				t.Errorf("failed to parse instrumented source: %v\n%s", err, instrumentedSrc)
+			}
+		})
+	}
+}
+
+func TestInstrumentModuleQuotesFile(t *testing.T) {
+	instrumentedSrc, _, err := InstrumentModule(`dir "quoted"/main.star`, "a = 1\n")
+	if err != nil {
+		t.Fatalf("failed to instrument module: %v", err)
+	}
+
+	expectedSrc := "__cover__(\"dir \\\"quoted\\\"/main.star\", 1); a = 1\n"
+	if instrumentedSrc != expectedSrc {
+		t.Errorf("expected instrumented source %q, got %q", expectedSrc, instrumentedSrc)
+	}
+}
+
+func TestInstrumentModuleSyntaxError(t *testing.T) {
+	_, _, err := InstrumentModule("main.star", "def broken(:\n")
+	if err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
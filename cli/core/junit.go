@@ -0,0 +1,150 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	junitFailureType     = "failure"
+	junitTracebackPrefix = "Traceback"
+)
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnitReport writes the results of a test run to reportPath as a JUnit XML document
+//
+// Every test file is represented by a testsuite element and every test function by a testcase element.
+// Skipped tests and expected failures are both reported as skipped testcases
+func WriteJUnitReport(summary *TestSuiteSummary, reportPath string) error {
+	report := junitTestSuites{
+		Name: summary.Project.KurotosisYml.PackageName,
+		Time: junitTime(summary.Duration()),
+	}
+
+	for _, testFileSummary := range summary.Summaries() {
+		testSuite := junitTestSuite{
+			Name: testFileSummary.TestFile.Path,
+			Time: junitTime(testFileSummary.Duration()),
+		}
+
+		for _, testFunctionSummary := range testFileSummary.Summaries() {
+			testCase := junitTestCase{
+				Name:      testFunctionSummary.TestFunction.DisplayName(),
+				ClassName: testFileSummary.TestFile.Path,
+				Time:      junitTime(testFunctionSummary.Duration()),
+			}
+
+			switch testFunctionSummary.Status() {
+			case TestStatusFail:
+				errorMessages := testFunctionSummary.ErrorMessages()
+
+				testCase.Failure = &junitFailure{
+					Message:  junitFailureMessage(errorMessages[0]),
+					Type:     junitFailureType,
+					Contents: strings.Join(errorMessages, "\n\n"),
+				}
+
+				testSuite.Failures++
+			case TestStatusSkip:
+				testCase.Skipped = &junitSkipped{
+					Message: testFunctionSummary.Reason(),
+				}
+
+				testSuite.Skipped++
+			case TestStatusXFail:
+				message := string(TestStatusXFail)
+				if testFunctionSummary.Reason() != "" {
+					message = fmt.Sprintf("%s: %s", message, testFunctionSummary.Reason())
+				}
+
+				testCase.Skipped = &junitSkipped{
+					Message: message,
+				}
+
+				testSuite.Skipped++
+			}
+
+			testSuite.Tests++
+			testSuite.TestCases = append(testSuite.TestCases, testCase)
+		}
+
+		report.Tests += testSuite.Tests
+		report.Failures += testSuite.Failures
+		report.Skipped += testSuite.Skipped
+		report.TestSuites = append(report.TestSuites, testSuite)
+	}
+
+	reportXml, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize JUnit report: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(reportPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for JUnit report %s: %w", reportPath, err)
+	}
+
+	err = os.WriteFile(reportPath, append([]byte(xml.Header), reportXml...), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write JUnit report to %s: %w", reportPath, err)
+	}
+
+	return nil
+}
+
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// Extracts a short failure message from a (potentially multi-line) error message
+//
+// Starlark tracebacks end with the actual error so we use the last line for those,
+// for any other errors the first line is used
+func junitFailureMessage(message string) string {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	if strings.HasPrefix(lines[0], junitTracebackPrefix) {
+		return lines[len(lines)-1]
+	}
+
+	return lines[0]
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/lib/enclaves"
+)
+
+func TestJunitFailureMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "single line",
+			message:  "assertion failed",
+			expected: "assertion failed",
+		},
+		{
+			name:     "multiple lines",
+			message:  "assertion failed\nsome details",
+			expected: "assertion failed",
+		},
+		{
+			name:     "traceback",
+			message:  "Traceback (most recent call last):\n  test.star:3:5: in test_something\nError: assertion failed",
+			expected: "Error: assertion failed",
+		},
+		{
+			name:     "surrounding whitespace",
+			message:  "\n  assertion failed\n",
+			expected: "assertion failed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := junitFailureMessage(test.message)
+			if actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteJUnitReportEscaping(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{name: "markup", message: `expected <service name="a"> & got </service>`},
+		{name: "quotes", message: `expected "a" but got 'b'`},
+		{name: "newlines", message: "first line\nsecond line\n\tindented line"},
+		{name: "cdata terminator", message: "expected ]]> to be escaped"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testFile := &TestFile{Path: "test/escaping_test.star"}
+
+			failingReporter := NewTestReporter(&TestFunction{TestFile: testFile, Name: "test_failing"})
+			failingReporter.Error(test.message)
+
+			skippedReporter := NewTestReporter(&TestFunction{TestFile: testFile, Name: "test_skipped"})
+			skippedReporter.Skip(test.message)
+
+			testFileSummary := NewTestFileSummary(testFile)
+			testFileSummary.Append(failingReporter.Summary())
+			testFileSummary.Append(skippedReporter.Summary())
+
+			summary := NewTestSuiteSummary(&KurtestosisProject{KurotosisYml: &enclaves.KurtosisYaml{PackageName: "github.com/kurtestosis/escaping"}})
+			summary.Append(testFileSummary)
+			summary.Finish()
+
+			reportPath := filepath.Join(t.TempDir(), "reports", "junit.xml")
+			err := WriteJUnitReport(summary, reportPath)
+			if err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+
+			content, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+
+			if !strings.HasPrefix(string(content), xml.Header) {
+				t.Errorf("expected the report to start with the XML header, got %q", content)
+			}
+
+			report := junitTestSuites{}
+			err = xml.Unmarshal(content, &report)
+			if err != nil {
+				t.Fatalf("failed to parse report: %v\n%s", err, content)
+			}
+
+			if len(report.TestSuites) != 1 || len(report.TestSuites[0].TestCases) != 2 {
+				t.Fatalf("expected a single testsuite with two testcases, got %+v", report)
+			}
+
+			failure := report.TestSuites[0].TestCases[0].Failure
+			if failure == nil {
+				t.Fatalf("expected the first testcase to have failed")
+			}
+			if failure.Contents != test.message {
+				t.Errorf("expected failure contents %q, got %q", test.message, failure.Contents)
+			}
+			if expectedMessage := junitFailureMessage(test.message); failure.Message != expectedMessage {
+				t.Errorf("expected failure message %q, got %q", expectedMessage, failure.Message)
+			}
+
+			skipped := report.TestSuites[0].TestCases[1].Skipped
+			if skipped == nil {
+				t.Fatalf("expected the second testcase to have been skipped")
+			}
+			if skipped.Message != test.message {
+				t.Errorf("expected skipped message %q, got %q", test.message, skipped.Message)
+			}
+
+			if report.Tests != 2 || report.Failures != 1 || report.Skipped != 1 {
+				t.Errorf("expected 2 tests, 1 failure and 1 skipped, got %d, %d and %d", report.Tests, report.Failures, report.Skipped)
+			}
+		})
+	}
+}
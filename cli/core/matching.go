@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"go.starlark.net/syntax"
@@ -19,15 +22,83 @@ func (testFile *TestFile) String() string {
 	return testFile.Path
 }
 
+// Prefix of a global variable that holds the parameter sets for a parametrized test function
+//
+// A test function called test_ports is parametrized by defining a PARAMS_test_ports list
+const TestParamsGlobalPrefix = "PARAMS_"
+
+// Prefix of a comment pragma that marks a test function
+//
+// Pragmas need to be placed on the lines directly above the test function definition, e.g.
+//
+//	# kurtestosis: skip not implemented yet
+//	def test_something(plan):
+const TestPragmaPrefix = "kurtestosis:"
+
+const (
+	TestPragmaSkip = "skip"
+	TestPragmaXFail = "xfail"
+	TestPragmaFocus = "focus"
+	TestPragmaExecute = "execute"
+)
+
+// TestMarkers are declared using comment pragmas above a test function
+type TestMarkers struct {
+	// The test function will not be run
+	Skip bool
+	// The test function is expected to fail
+	XFail bool
+	// Only the focused test functions will be run
+	Focus bool
+	// The plan instructions will be executed against the in-memory service network as the test adds them
+	Execute bool
+	// Reason given for the skip or xfail marker
+	Reason string
+}
+
 type TestFunction struct {
 	TestFile *TestFile
 	Name string
+	// Line of the def statement of the test function in its test file
+	Line int32
+	Markers TestMarkers
+	// Whether the test function runs with a parameter set from its params global
+	Parametrized bool
+	// Index of the parameter set in the params global
+	ParamsIndex int
+}
+
+// ParamsGlobalName returns the name of the global variable holding the parameter sets for this test function
+func (testFunction *TestFunction) ParamsGlobalName() string {
+	return TestParamsGlobalPrefix + testFunction.Name
+}
+
+// DisplayName returns the function name along with the parameter set index for parametrized test functions
+func (testFunction *TestFunction) DisplayName() string {
+	if testFunction.Parametrized {
+		return fmt.Sprintf("%s[%d]", testFunction.Name, testFunction.ParamsIndex)
+	}
+
+	return testFunction.Name
 }
 
 func (testFunction *TestFunction) String() string {
-	return fmt.Sprintf("%s:%s", testFunction.TestFile, testFunction.Name)
+	return fmt.Sprintf("%s:%s", testFunction.TestFile, testFunction.DisplayName())
+}
+
+// SkippedTestFunction is a function that was found in a test file but will not be run
+type SkippedTestFunction struct {
+	TestFunction *TestFunction
+	Reason string
+}
+
+func (skippedTestFunction *SkippedTestFunction) String() string {
+	return fmt.Sprintf("%s (%s)", skippedTestFunction.TestFunction, skippedTestFunction.Reason)
 }
 
+// ListMatchingTestFiles finds the test files in a project that match the testFilePattern
+//
+// The test files are sorted by their paths since the order of the globbing results is not stable
 func ListMatchingTestFiles(project *KurtestosisProject, testFilePattern string) ([]*TestFile, error) {
 	// The testFilePattern is expected to be a relative path from the project root
 	// so we first need to make sure it will only match inside the project root
@@ -50,6 +121,11 @@ func ListMatchingTestFiles(project *KurtestosisProject, testFilePattern string)
 			logrus.Debugf("Skipping matched test file %s because it's a directory", testFilePath); continue
 		}
 
+		// The test files of vendored packages are not part of the project
+		if strings.HasPrefix(testFilePath, project.VendorDirPath() + string(filepath.Separator)) {
+			logrus.Debugf("Skipping matched test file %s because it's vendored", testFilePath); continue
+		}
+
 		logrus.Debugf("Matched test file %s", testFilePath)
 
 		testFilePathRel, testFilePathRelErr := filepath.Rel(project.Path, testFilePath)
@@ -63,31 +139,40 @@ func ListMatchingTestFiles(project *KurtestosisProject, testFilePattern string)
 		})
     }
 
+	sort.Slice(testFiles, func(i, j int) bool {
+		return testFiles[i].Path < testFiles[j].Path
+	})
+
 	logrus.Debugf("Matched %d test files", len(testFiles))
 
 	return testFiles, nil
 }
 
-func ListMatchingTests(testFile *TestFile, testPattern string) ([]*TestFunction, error) {
+// ListMatchingTests finds all the test functions in a test file
+//
+// Besides the test functions, all the other top-level functions are returned along with the reason why they were skipped
+func ListMatchingTests(testFile *TestFile, testPattern string) ([]*TestFunction, []*SkippedTestFunction, error) {
 	// First we read the contents of the test file
 	testFilePath := filepath.Join(testFile.Project.Path, testFile.Path)
 	testScript, testScriptErr := os.ReadFile(testFilePath)
 	if testScriptErr != nil {
 		logrus.Errorf("Failed to read test suite %s: %v", testFilePath, testScriptErr)
 
-		return nil, fmt.Errorf("failed to read test suite %s: %w", testFilePath, testScriptErr)
+		return nil, nil, fmt.Errorf("failed to read test suite %s: %w", testFilePath, testScriptErr)
 	}
 
 	// Now we parse the script contents into a starlark syntax tree
-	testParseTree, testParseTreeErr := syntax.Parse(testFile.Path, testScript, 0)
+	testParseTree, testParseTreeErr := syntax.Parse(testFile.Path, testScript, syntax.RetainComments)
 	if testParseTreeErr != nil {
 		logrus.Errorf("Failed to parse test suite %s: %v", testFile.Path, testParseTreeErr)
 
-		return nil, fmt.Errorf("failed to parse test suite %s: %w", testFile.Path, testParseTreeErr)
+		return nil, nil, fmt.Errorf("failed to parse test suite %s: %w", testFile.Path, testParseTreeErr)
 	}
 
 	// Now we walk the starlark tree looking for top-level def statements
+	// and top-level assignments of test parameter sets
 	defStmts := []*syntax.DefStmt{}
+	paramsAssignStmts := map[string]*syntax.AssignStmt{}
 	syntax.Walk(testParseTree, func(node syntax.Node) bool {
 		// If we are looking at the top-level file node, we just continue
 		// since we need to look inside the file
@@ -102,6 +187,15 @@ func ListMatchingTests(testFile *TestFile, testPattern string) ([]*TestFunction,
 			return false
         }
 
+		// If we found an assignment to a params global, we remember it as well
+		if assignStmt, ok := node.(*syntax.AssignStmt); ok && assignStmt.Op == syntax.EQ {
+			if ident, ok := assignStmt.LHS.(*syntax.Ident); ok && strings.HasPrefix(ident.Name, TestParamsGlobalPrefix) {
+				paramsAssignStmts[ident.Name] = assignStmt
+			}
+
+			return false
+		}
+
 		// For any other nodes we'll not traverse further since we are only looking for top-level test methods
 		return false
 	})
@@ -111,23 +205,247 @@ func ListMatchingTests(testFile *TestFile, testPattern string) ([]*TestFunction,
 
 	// Now let's filter out the test functions
 	testFunctions := []*TestFunction{}
+	skippedTestFunctions := []*SkippedTestFunction{}
 	for _, defStmt := range(defStmts) {
+		testFunction := &TestFunction{
+			TestFile: testFile,
+			Name: defStmt.Name.Name,
+			Line: defStmt.Def.Line,
+			Markers: parseTestMarkers(defStmt, testFile),
+		}
+
 		// First we check that the test function's name matches the test pattern
 		if !testRegexp.MatchString(defStmt.Name.Name) {
-			logrus.Debugf("Function %s from %s does not match test pattern %s, skipping", defStmt.Name.Name, testFile.Path, testPattern); continue
+			logrus.Debugf("Function %s from %s does not match test pattern %s, skipping", defStmt.Name.Name, testFile.Path, testPattern)
+
+			skippedTestFunctions = append(skippedTestFunctions, &SkippedTestFunction{
+				TestFunction: testFunction,
+				Reason: fmt.Sprintf("does not match test pattern %s", testPattern),
+			}); continue
 		}
 
-		// Now we make sure that the function accepts one parameter
+		// Parametrized test functions are expanded into one test function per parameter set
 		numParams := len(defStmt.Params)
+		if paramsAssignStmt, parametrized := paramsAssignStmts[testFunction.ParamsGlobalName()]; parametrized {
+			parametrizedTestFunctions, skipReason := expandParametrizedTest(testFunction, numParams, paramsAssignStmt)
+			if skipReason != "" {
+				logrus.Warnf("Function %s from %s matches test pattern %s but %s", defStmt.Name.Name, testFile.Path, testPattern, skipReason)
+
+				skippedTestFunctions = append(skippedTestFunctions, &SkippedTestFunction{
+					TestFunction: testFunction,
+					Reason: skipReason,
+				}); continue
+			}
+
+			testFunctions = append(testFunctions, parametrizedTestFunctions...); continue
+		}
+
+		// Now we make sure that the function accepts one parameter
 		if numParams != 1 {
-			logrus.Warnf("Function %s from %s matches test pattern %s but accepts %d params instead of 1. Test functions should accept only plan param", defStmt.Name.Name, testFile.Path, testPattern, numParams); continue
+			logrus.Warnf("Function %s from %s matches test pattern %s but accepts %d params instead of 1. Test functions should accept only plan param, parametrized test functions should accept plan and params", defStmt.Name.Name, testFile.Path, testPattern, numParams)
+
+			skippedTestFunctions = append(skippedTestFunctions, &SkippedTestFunction{
+				TestFunction: testFunction,
+				Reason: fmt.Sprintf("accepts %d params instead of 1", numParams),
+			}); continue
+		}
+
+		testFunctions = append(testFunctions, testFunction)
+	}
+
+	return testFunctions, skippedTestFunctions, nil
+}
+
+// Reads the test markers from the comment pragmas directly above a def statement
+func parseTestMarkers(defStmt *syntax.DefStmt, testFile *TestFile) TestMarkers {
+	markers := TestMarkers{}
+	if defStmt.Comments() == nil {
+		return markers
+	}
+
+	for _, comment := range defStmt.Comments().Before {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "#"))
+		pragma, isPragma := strings.CutPrefix(text, TestPragmaPrefix)
+		if !isPragma {
+			continue
+		}
+
+		marker, reason, _ := strings.Cut(strings.TrimSpace(pragma), " ")
+		switch marker {
+		case TestPragmaSkip:
+			markers.Skip = true
+			markers.Reason = strings.TrimSpace(reason)
+		case TestPragmaXFail:
+			markers.XFail = true
+			markers.Reason = strings.TrimSpace(reason)
+		case TestPragmaFocus:
+			markers.Focus = true
+		case TestPragmaExecute:
+			markers.Execute = true
+		default:
+			logrus.Warnf("Unknown pragma %s above function %s in %s, ignoring", text, defStmt.Name.Name, testFile.Path)
+		}
+	}
+
+	return markers
+}
+
+// FilterFocusedTests narrows the test functions down to the focused ones
+//
+// If none of the test functions are focused, all of them are returned
+func FilterFocusedTests(testFunctions []*TestFunction) ([]*TestFunction, []*SkippedTestFunction) {
+	focusedTestFunctions := []*TestFunction{}
+	for _, testFunction := range testFunctions {
+		if testFunction.Markers.Focus {
+			focusedTestFunctions = append(focusedTestFunctions, testFunction)
+		}
+	}
+
+	if len(focusedTestFunctions) == 0 {
+		return testFunctions, []*SkippedTestFunction{}
+	}
+
+	skippedTestFunctions := []*SkippedTestFunction{}
+	for _, testFunction := range testFunctions {
+		if !testFunction.Markers.Focus {
+			logrus.Debugf("Test %s is not focused, skipping", testFunction)
+
+			skippedTestFunctions = append(skippedTestFunctions, &SkippedTestFunction{
+				TestFunction: testFunction,
+				Reason: "not focused",
+			})
 		}
+	}
+
+	return focusedTestFunctions, skippedTestFunctions
+}
+
+// Expands a parametrized test function into one test function per parameter set
+//
+// Since test files are not executed during discovery, the parameter sets need to be defined using a list literal
+// so that they can be counted. If the test function cannot be expanded, the reason is returned instead
+func expandParametrizedTest(testFunction *TestFunction, numParams int, paramsAssignStmt *syntax.AssignStmt) ([]*TestFunction, string) {
+	if numParams != 2 {
+		return nil, fmt.Sprintf("accepts %d params instead of 2 even though %s is defined", numParams, testFunction.ParamsGlobalName())
+	}
+
+	paramsList, ok := paramsAssignStmt.RHS.(*syntax.ListExpr)
+	if !ok {
+		return nil, fmt.Sprintf("%s is not a list literal", testFunction.ParamsGlobalName())
+	}
+
+	if len(paramsList.List) == 0 {
+		return nil, fmt.Sprintf("%s is empty", testFunction.ParamsGlobalName())
+	}
 
+	testFunctions := []*TestFunction{}
+	for paramsIndex := range paramsList.List {
 		testFunctions = append(testFunctions, &TestFunction{
-			TestFile: testFile,
-			Name: defStmt.Name.Name,
+			TestFile: testFunction.TestFile,
+			Name: testFunction.Name,
+			Line: testFunction.Line,
+			Markers: testFunction.Markers,
+			Parametrized: true,
+			ParamsIndex: paramsIndex,
 		})
 	}
 
-	return testFunctions, nil
-}
\ No newline at end of file
+	return testFunctions, ""
+}
+
+// TestSelector narrows down the discovered test functions to the ones explicitly requested by the user
+//
+// Test functions can be selected using a regular expression matched against the full test ID
+// and/or a list of test IDs. A test ID can either be in <test file>:<test function> format
+// or just a path to a test file, in which case all the test functions from that file are selected.
+// Parametrized test functions can be selected one parameter set at a time (<test file>:<test function>[<index>])
+// or all at once (<test file>:<test function>).
+type TestSelector struct {
+	runRegexp *regexp.Regexp
+	testIDs []string
+	matchedTestIDs map[string]bool
+}
+
+func NewTestSelector(runPattern string, testIDs []string) (*TestSelector, error) {
+	var runRegexp *regexp.Regexp
+	if runPattern != "" {
+		var runRegexpErr error
+		runRegexp, runRegexpErr = regexp.Compile(runPattern)
+		if runRegexpErr != nil {
+			return nil, fmt.Errorf("invalid test run pattern %s: %w", runPattern, runRegexpErr)
+		}
+	}
+
+	// We normalize the test file paths in test IDs so that e.g. ./test/my_test.star matches test/my_test.star
+	normalizedTestIDs := make([]string, len(testIDs))
+	for i, testID := range testIDs {
+		testFilePath, testFunctionName, hasTestFunctionName := strings.Cut(testID, ":")
+		normalizedTestIDs[i] = filepath.Clean(testFilePath)
+		if hasTestFunctionName {
+			normalizedTestIDs[i] = fmt.Sprintf("%s:%s", normalizedTestIDs[i], testFunctionName)
+		}
+	}
+
+	return &TestSelector{
+		runRegexp: runRegexp,
+		testIDs: normalizedTestIDs,
+		matchedTestIDs: map[string]bool{},
+	}, nil
+}
+
+// IsExplicit returns true if the user requested specific tests to run
+func (selector *TestSelector) IsExplicit() bool {
+	return selector.runRegexp != nil || len(selector.testIDs) > 0
+}
+
+func (selector *TestSelector) Matches(testFunction *TestFunction) bool {
+	if selector.runRegexp != nil && !selector.runRegexp.MatchString(testFunction.String()) {
+		return false
+	}
+
+	if len(selector.testIDs) == 0 {
+		return true
+	}
+
+	matches := false
+	for _, testID := range selector.testIDs {
+		if testID == testFunction.String() || testID == testFunction.TestFile.Path || testID == fmt.Sprintf("%s:%s", testFunction.TestFile, testFunction.Name) {
+			selector.matchedTestIDs[testID] = true
+			matches = true
+		}
+	}
+
+	return matches
+}
+
+// Filter splits the test functions into the ones that match the selector and the ones that don't
+func (selector *TestSelector) Filter(testFunctions []*TestFunction) ([]*TestFunction, []*SkippedTestFunction) {
+	selectedTestFunctions := []*TestFunction{}
+	skippedTestFunctions := []*SkippedTestFunction{}
+	for _, testFunction := range testFunctions {
+		if !selector.Matches(testFunction) {
+			logrus.Debugf("Test %s was not selected to run, skipping", testFunction)
+
+			skippedTestFunctions = append(skippedTestFunctions, &SkippedTestFunction{
+				TestFunction: testFunction,
+				Reason: "not selected",
+			}); continue
+		}
+
+		selectedTestFunctions = append(selectedTestFunctions, testFunction)
+	}
+
+	return selectedTestFunctions, skippedTestFunctions
+}
+
+// UnmatchedTestIDs returns the test IDs that did not match any of the test functions passed to Matches or Filter
+func (selector *TestSelector) UnmatchedTestIDs() []string {
+	unmatchedTestIDs := []string{}
+	for _, testID := range selector.testIDs {
+		if !selector.matchedTestIDs[testID] {
+			unmatchedTestIDs = append(unmatchedTestIDs, testID)
+		}
+	}
+
+	return unmatchedTestIDs
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTestSelector(t *testing.T) {
+	firstTestFile := &TestFile{Path: "test/first_test.star"}
+	secondTestFile := &TestFile{Path: "test/second_test.star"}
+
+	testFunctions := []*TestFunction{
+		{TestFile: firstTestFile, Name: "test_one"},
+		{TestFile: firstTestFile, Name: "test_two"},
+		{TestFile: firstTestFile, Name: "test_params", Parametrized: true, ParamsIndex: 0},
+		{TestFile: firstTestFile, Name: "test_params", Parametrized: true, ParamsIndex: 1},
+		{TestFile: secondTestFile, Name: "test_one"},
+	}
+
+	tests := []struct {
+		name              string
+		runPattern        string
+		testIDs           []string
+		expectedExplicit  bool
+		expectedSelected  []string
+		expectedUnmatched []string
+	}{
+		{
+			name:             "no selection",
+			expectedExplicit: false,
+			expectedSelected: []string{
+				"test/first_test.star:test_one",
+				"test/first_test.star:test_two",
+				"test/first_test.star:test_params[0]",
+				"test/first_test.star:test_params[1]",
+				"test/second_test.star:test_one",
+			},
+		},
+		{
+			name:             "run pattern",
+			runPattern:       "test_one$",
+			expectedExplicit: true,
+			expectedSelected: []string{"test/first_test.star:test_one", "test/second_test.star:test_one"},
+		},
+		{
+			name:             "run pattern matching parameter sets",
+			runPattern:       `\[1\]`,
+			expectedExplicit: true,
+			expectedSelected: []string{"test/first_test.star:test_params[1]"},
+		},
+		{
+			name:             "test file",
+			testIDs:          []string{"test/second_test.star"},
+			expectedExplicit: true,
+			expectedSelected: []string{"test/second_test.star:test_one"},
+		},
+		{
+			name:             "test function",
+			testIDs:          []string{"test/first_test.star:test_two"},
+			expectedExplicit: true,
+			expectedSelected: []string{"test/first_test.star:test_two"},
+		},
+		{
+			name:             "unnormalized test file path",
+			testIDs:          []string{"./test/../test/first_test.star:test_one"},
+			expectedExplicit: true,
+			expectedSelected: []string{"test/first_test.star:test_one"},
+		},
+		{
+			name:             "all parameter sets",
+			testIDs:          []string{"test/first_test.star:test_params"},
+			expectedExplicit: true,
+			expectedSelected: []string{"test/first_test.star:test_params[0]", "test/first_test.star:test_params[1]"},
+		},
+		{
+			name:             "single parameter set",
+			testIDs:          []string{"test/first_test.star:test_params[1]"},
+			expectedExplicit: true,
+			expectedSelected: []string{"test/first_test.star:test_params[1]"},
+		},
+		{
+			name:              "missing parameter set",
+			testIDs:           []string{"test/first_test.star:test_params[2]"},
+			expectedExplicit:  true,
+			expectedSelected:  []string{},
+			expectedUnmatched: []string{"test/first_test.star:test_params[2]"},
+		},
+		{
+			name:              "multiple test IDs",
+			testIDs:           []string{"test/first_test.star:test_one", "test/second_test.star", "test/missing_test.star"},
+			expectedExplicit:  true,
+			expectedSelected:  []string{"test/first_test.star:test_one", "test/second_test.star:test_one"},
+			expectedUnmatched: []string{"test/missing_test.star"},
+		},
+		{
+			name:              "run pattern and test IDs",
+			runPattern:        "test_two",
+			testIDs:           []string{"test/first_test.star", "test/second_test.star"},
+			expectedExplicit:  true,
+			expectedSelected:  []string{"test/first_test.star:test_two"},
+			expectedUnmatched: []string{"test/second_test.star"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := NewTestSelector(test.runPattern, test.testIDs)
+			if err != nil {
+				t.Fatalf("failed to create selector: %v", err)
+			}
+
+			if selector.IsExplicit() != test.expectedExplicit {
+				t.Errorf("expected IsExplicit to be %v", test.expectedExplicit)
+			}
+
+			selectedTestFunctions, skippedTestFunctions := selector.Filter(testFunctions)
+
+			selected := []string{}
+			for _, testFunction := range selectedTestFunctions {
+				selected = append(selected, testFunction.String())
+			}
+			if !reflect.DeepEqual(selected, test.expectedSelected) {
+				t.Errorf("expected selected tests %q, got %q", test.expectedSelected, selected)
+			}
+
+			if len(selectedTestFunctions)+len(skippedTestFunctions) != len(testFunctions) {
+				t.Errorf("expected every test function to be either selected or skipped, got %d selected and %d skipped", len(selectedTestFunctions), len(skippedTestFunctions))
+			}
+
+			unmatched := selector.UnmatchedTestIDs()
+			if len(unmatched) != 0 || len(test.expectedUnmatched) != 0 {
+				if !reflect.DeepEqual(unmatched, test.expectedUnmatched) {
+					t.Errorf("expected unmatched test IDs %q, got %q", test.expectedUnmatched, unmatched)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTestSelectorInvalidRunPattern(t *testing.T) {
+	_, err := NewTestSelector("test_[", []string{})
+	if err == nil {
+		t.Errorf("expected an invalid run pattern to fail")
+	}
+}
+
+func TestListMatchingTestFiles(t *testing.T) {
+	projectPath := t.TempDir()
+	for _, path := range []string{
+		"z_test.star",
+		"a_test.star",
+		"b/c_test.star",
+		"b/a_spec.star",
+		"a/z_test.star",
+		"sut.star",
+		"dir_test.star/module.star",
+	} {
+		writeTestFile(t, filepath.Join(projectPath, path), "")
+	}
+
+	tests := []struct {
+		name            string
+		testFilePattern string
+		expected        []string
+	}{
+		{
+			name:            "all test files",
+			testFilePattern: "**/*_{test,spec}.star",
+			expected:        []string{"a/z_test.star", "a_test.star", "b/a_spec.star", "b/c_test.star", "z_test.star"},
+		},
+		{
+			name:            "test files in a directory",
+			testFilePattern: "b/*.star",
+			expected:        []string{"b/a_spec.star", "b/c_test.star"},
+		},
+		{
+			name:            "single test file",
+			testFilePattern: "z_test.star",
+			expected:        []string{"z_test.star"},
+		},
+		{
+			name:            "no matches",
+			testFilePattern: "**/*_missing.star",
+			expected:        []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project := &KurtestosisProject{Path: projectPath}
+
+			testFiles, err := ListMatchingTestFiles(project, test.testFilePattern)
+			if err != nil {
+				t.Fatalf("failed to list test files: %v", err)
+			}
+
+			paths := []string{}
+			for _, testFile := range testFiles {
+				paths = append(paths, filepath.ToSlash(testFile.Path))
+			}
+
+			if !reflect.DeepEqual(paths, test.expected) {
+				t.Errorf("expected test files %q, got %q", test.expected, paths)
+			}
+		})
+	}
+}
+
+// Writes a file along with its parent directories
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatalf("failed to create directory for %s: %v", path, err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
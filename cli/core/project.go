@@ -2,7 +2,10 @@ package core
 
 import (
 	"fmt"
+	"io/fs"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/kurtosis-tech/kurtosis/api/golang/core/lib/enclaves"
 	"github.com/sirupsen/logrus"
@@ -11,6 +14,10 @@ import (
 type KurtestosisProject struct {
 	KurotosisYml *enclaves.KurtosisYaml
 	Path string
+	// Absolute paths of the packages replaced with local directories, by package name
+	LocalReplaces map[string]string
+	// The remote packages vendored into the project, empty if the project has not vendored any
+	Lockfile *Lockfile
 }
 
 func LoadKurtestosisProject(projectPath string) (*KurtestosisProject, error) {
@@ -34,8 +41,146 @@ func LoadKurtestosisProject(projectPath string) (*KurtestosisProject, error) {
 	}
 	logrus.Debugf("Loaded kurtosis config from %s", kurtosisYamlFilepath)
 
-	return &KurtestosisProject{
+	// The replace options of the packages replaced with local directories apply too,
+	// so we merge them into the project replace options
+	packageReplaceOptions, localReplaces, localReplacesErr := loadLocalReplaces(projectPathAbsolute, kurtosisYml.PackageReplaceOptions)
+	if localReplacesErr != nil {
+		return nil, localReplacesErr
+	}
+	kurtosisYml.PackageReplaceOptions = packageReplaceOptions
+
+	project := &KurtestosisProject{
 		KurotosisYml: kurtosisYml,
 		Path: projectPathAbsolute,
-	}, nil
-}
\ No newline at end of file
+		LocalReplaces: localReplaces,
+	}
+
+	lockfile, lockfileErr := ReadLockfile(project)
+	if lockfileErr != nil {
+		return nil, lockfileErr
+	}
+	project.Lockfile = lockfile
+
+	return project, nil
+}
+
+// LocalReplacePath returns the path of a file from a package that has been replaced with a local directory
+//
+// Locators of the package itself, e.g. the ones passed to plan.upload_files, map to the local directory
+func (project *KurtestosisProject) LocalReplacePath(locator string) (string, bool) {
+	// We look for the longest package name the locator starts with, e.g. for github.com/author/package/path/file.star
+	// we'd try github.com/author/package/path/file.star first, then github.com/author/package/path and github.com/author/package
+	packageName := locator
+	for strings.Count(packageName, "/") >= 2 {
+		if localPath, ok := project.LocalReplaces[packageName]; ok {
+			return filepath.Join(localPath, strings.TrimPrefix(locator, packageName)), true
+		}
+
+		packageName = packageName[:strings.LastIndex(packageName, "/")]
+	}
+
+	return "", false
+}
+
+// Collects the packages replaced with local directories, following the replace options of the local packages as well
+//
+// Returns the merged replace options, with the local directories turned into absolute paths, along with the local replaces.
+// The replace options of the project take precedence over the ones of its dependencies
+func loadLocalReplaces(projectPath string, projectReplaceOptions map[string]string) (map[string]string, map[string]string, error) {
+	packageReplaceOptions := map[string]string{}
+	localReplaces := map[string]string{}
+
+	type localPackage struct {
+		path string
+		replaceOptions map[string]string
+	}
+
+	localPackages := []localPackage{{path: projectPath, replaceOptions: projectReplaceOptions}}
+	for len(localPackages) > 0 {
+		current := localPackages[0]
+		localPackages = localPackages[1:]
+
+		// The replace options are sorted so that conflicting dependencies are resolved the same way every time
+		packageNames := []string{}
+		for packageName := range current.replaceOptions {
+			packageNames = append(packageNames, packageName)
+		}
+		sort.Strings(packageNames)
+
+		for _, packageName := range packageNames {
+			if _, ok := packageReplaceOptions[packageName]; ok {
+				continue
+			}
+
+			replace := current.replaceOptions[packageName]
+			if !isLocalReplace(replace) {
+				packageReplaceOptions[packageName] = replace
+				continue
+			}
+
+			// Local directories are relative to the package that replaces them
+			localPath := replace
+			if !filepath.IsAbs(localPath) {
+				localPath = filepath.Join(current.path, localPath)
+			}
+
+			packageReplaceOptions[packageName] = localPath
+			localReplaces[packageName] = localPath
+			logrus.Debugf("Package %s is replaced with local directory %s", packageName, localPath)
+
+			kurtosisYamlFilepath := filepath.Join(localPath, "kurtosis.yml")
+			kurtosisYml, kurtosisYmlErr := enclaves.ParseKurtosisYaml(kurtosisYamlFilepath)
+			if kurtosisYmlErr != nil {
+				return nil, nil, fmt.Errorf("failed to load kurtosis.yml of package %s replaced with %s: %w", packageName, localPath, kurtosisYmlErr)
+			}
+
+			localPackages = append(localPackages, localPackage{path: localPath, replaceOptions: kurtosisYml.PackageReplaceOptions})
+		}
+	}
+
+	return packageReplaceOptions, localReplaces, nil
+}
+
+// Kurtosis treats replace options starting with / or . as local directories
+func isLocalReplace(replace string) bool {
+	return strings.HasPrefix(replace, "/") || strings.HasPrefix(replace, ".")
+}
+
+// ListProjectModules finds all the starlark modules of a project, including the test files
+//
+// The vendor directory and hidden directories like the temp directory are skipped.
+// The module paths are relative to the project root and sorted
+func ListProjectModules(project *KurtestosisProject) ([]string, error) {
+	modulePaths := []string{}
+	err := filepath.WalkDir(project.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != project.Path && (path == project.VendorDirPath() || strings.HasPrefix(entry.Name(), ".")) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(path) != ".star" {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(project.Path, path)
+		if err != nil {
+			return err
+		}
+
+		modulePaths = append(modulePaths, relativePath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the modules of project %s: %w", project.Path, err)
+	}
+
+	return modulePaths, nil
+}
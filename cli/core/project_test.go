@@ -0,0 +1,175 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLocalReplacePath(t *testing.T) {
+	project := &KurtestosisProject{
+		LocalReplaces: map[string]string{
+			"github.com/org/package":                  "/local/package",
+			"github.com/org/monorepo/packages":        "/local/packages",
+			"github.com/org/monorepo/packages/nested": "/local/nested",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		locator      string
+		expectedPath string
+		expectedOk   bool
+	}{
+		{
+			name:         "file",
+			locator:      "github.com/org/package/main.star",
+			expectedPath: "/local/package/main.star",
+			expectedOk:   true,
+		},
+		{
+			name:         "nested file",
+			locator:      "github.com/org/package/lib/helpers.star",
+			expectedPath: "/local/package/lib/helpers.star",
+			expectedOk:   true,
+		},
+		{
+			name:         "package itself",
+			locator:      "github.com/org/package",
+			expectedPath: "/local/package",
+			expectedOk:   true,
+		},
+		{
+			name:         "package in a subdirectory",
+			locator:      "github.com/org/monorepo/packages/main.star",
+			expectedPath: "/local/packages/main.star",
+			expectedOk:   true,
+		},
+		{
+			name:         "longest package name",
+			locator:      "github.com/org/monorepo/packages/nested/main.star",
+			expectedPath: "/local/nested/main.star",
+			expectedOk:   true,
+		},
+		{
+			name:         "longest package itself",
+			locator:      "github.com/org/monorepo/packages/nested",
+			expectedPath: "/local/nested",
+			expectedOk:   true,
+		},
+		{
+			name:       "package name prefix",
+			locator:    "github.com/org/package-other/main.star",
+			expectedOk: false,
+		},
+		{
+			name:       "parent of a replaced package",
+			locator:    "github.com/org/monorepo/main.star",
+			expectedOk: false,
+		},
+		{
+			name:       "other package",
+			locator:    "github.com/other/package/main.star",
+			expectedOk: false,
+		},
+		{
+			name:       "organization",
+			locator:    "github.com/org",
+			expectedOk: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, ok := project.LocalReplacePath(test.locator)
+			if ok != test.expectedOk {
+				t.Fatalf("expected LocalReplacePath(%q) to return %v, got %v (%s)", test.locator, test.expectedOk, ok, path)
+			}
+
+			if ok && path != filepath.FromSlash(test.expectedPath) {
+				t.Errorf("expected path %s, got %s", test.expectedPath, path)
+			}
+		})
+	}
+}
+
+func TestLoadLocalReplaces(t *testing.T) {
+	root := t.TempDir()
+	projectPath := filepath.Join(root, "project")
+	localPath := filepath.Join(root, "local")
+	nestedPath := filepath.Join(root, "nested")
+	overriddenPath := filepath.Join(root, "overridden")
+
+	writeTestFile(t, filepath.Join(projectPath, "kurtosis.yml"), "name: github.com/org/project\n")
+	writeTestFile(t, filepath.Join(localPath, "kurtosis.yml"), `name: github.com/org/local
+replace:
+  github.com/org/nested: ../nested
+  github.com/org/overridden: ../local-overridden
+  github.com/org/remote-from-local: github.com/fork/remote-from-local
+`)
+	writeTestFile(t, filepath.Join(nestedPath, "kurtosis.yml"), "name: github.com/org/nested\n")
+	writeTestFile(t, filepath.Join(overriddenPath, "kurtosis.yml"), "name: github.com/org/overridden\n")
+
+	projectReplaceOptions := map[string]string{
+		"github.com/org/local":      "../local",
+		"github.com/org/overridden": "../overridden",
+		"github.com/org/remote":     "github.com/fork/remote",
+	}
+
+	packageReplaceOptions, localReplaces, err := loadLocalReplaces(projectPath, projectReplaceOptions)
+	if err != nil {
+		t.Fatalf("failed to load local replaces: %v", err)
+	}
+
+	// Local directories are relative to the kurtosis.yml that declares them
+	// and the replace options of the project take precedence over the ones of its dependencies
+	expectedLocalReplaces := map[string]string{
+		"github.com/org/local":      localPath,
+		"github.com/org/nested":     nestedPath,
+		"github.com/org/overridden": overriddenPath,
+	}
+	if !reflect.DeepEqual(localReplaces, expectedLocalReplaces) {
+		t.Errorf("expected local replaces %v, got %v", expectedLocalReplaces, localReplaces)
+	}
+
+	expectedPackageReplaceOptions := map[string]string{
+		"github.com/org/local":             localPath,
+		"github.com/org/nested":            nestedPath,
+		"github.com/org/overridden":        overriddenPath,
+		"github.com/org/remote":            "github.com/fork/remote",
+		"github.com/org/remote-from-local": "github.com/fork/remote-from-local",
+	}
+	if !reflect.DeepEqual(packageReplaceOptions, expectedPackageReplaceOptions) {
+		t.Errorf("expected package replace options %v, got %v", expectedPackageReplaceOptions, packageReplaceOptions)
+	}
+}
+
+func TestLoadLocalReplacesMissingPackage(t *testing.T) {
+	projectPath := t.TempDir()
+
+	_, _, err := loadLocalReplaces(projectPath, map[string]string{"github.com/org/missing": "./missing"})
+	if err == nil {
+		t.Errorf("expected a local replace without a kurtosis.yml to fail")
+	}
+}
+
+func TestIsLocalReplace(t *testing.T) {
+	tests := []struct {
+		replace  string
+		expected bool
+	}{
+		{replace: "../local", expected: true},
+		{replace: "./local", expected: true},
+		{replace: "/absolute/local", expected: true},
+		{replace: "github.com/fork/package", expected: false},
+		{replace: "github.com/fork/package@v1.2", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.replace, func(t *testing.T) {
+			if isLocalReplace(test.replace) != test.expected {
+				t.Errorf("expected isLocalReplace(%q) to be %v", test.replace, test.expected)
+			}
+		})
+	}
+}
@@ -1,18 +1,52 @@
 package core
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
 type TestError = []interface{}
 
+// ErrTestSkipped stops a test function skipped using kurtestosis.skip
+var ErrTestSkipped = errors.New("kurtestosis: test skipped")
+
+// TestStatus is the outcome of a single test function
+type TestStatus string
+
+const (
+	TestStatusPass TestStatus = "pass"
+	TestStatusFail TestStatus = "fail"
+	// The test function was skipped, either using a pragma or kurtestosis.skip
+	TestStatusSkip TestStatus = "skip"
+	// The test function was expected to fail and it did
+	TestStatusXFail TestStatus = "xfail"
+	// The test function was expected to fail but it passed
+	TestStatusXPass TestStatus = "xpass"
+)
+
 type TestSuiteSummary struct {
 	Project *KurtestosisProject
 	summaries []TestFileSummary
+	startTime time.Time
+	endTime time.Time
 }
 
+// NewTestSuiteSummary creates the summary of a test run that starts right away
 func NewTestSuiteSummary(project *KurtestosisProject) *TestSuiteSummary {
 	return &TestSuiteSummary{
 		Project: project,
+		startTime: time.Now(),
 	}
 }
 
+// Finish marks the end of the test run
+func (summary *TestSuiteSummary) Finish() {
+	summary.endTime = time.Now()
+}
+
 func (summary *TestSuiteSummary) Append(testFileSummary *TestFileSummary) {
 	summary.summaries = append(summary.summaries, *testFileSummary)
 }
@@ -31,6 +65,52 @@ func (summary *TestSuiteSummary) Success() bool {
 	return true
 }
 
+// Counts returns the number of tests with each status across the whole test run
+func (summary *TestSuiteSummary) Counts() TestCounts {
+	counts := TestCounts{}
+	for _, testFileSummary := range(summary.summaries) {
+		for _, testFunctionSummary := range(testFileSummary.summaries) {
+			counts.add(testFunctionSummary.Status())
+		}
+	}
+
+	return counts
+}
+
+// Duration returns the wall-clock duration of the test run
+//
+// With test functions running in parallel, this is less than the sum of their durations
+func (summary *TestSuiteSummary) Duration() time.Duration {
+	if summary.endTime.IsZero() {
+		return time.Since(summary.startTime)
+	}
+
+	return summary.endTime.Sub(summary.startTime)
+}
+
+type TestCounts struct {
+	Passed int
+	Failed int
+	Skipped int
+	XFailed int
+	XPassed int
+}
+
+func (counts *TestCounts) add(status TestStatus) {
+	switch status {
+	case TestStatusPass:
+		counts.Passed++
+	case TestStatusFail:
+		counts.Failed++
+	case TestStatusSkip:
+		counts.Skipped++
+	case TestStatusXFail:
+		counts.XFailed++
+	case TestStatusXPass:
+		counts.XPassed++
+	}
+}
+
 type TestFileSummary struct {
 	TestFile *TestFile
 	summaries []TestFunctionSummary
@@ -54,38 +134,240 @@ func (summary *TestFileSummary) Success() bool {
 	return true
 }
 
+func (summary *TestFileSummary) Duration() time.Duration {
+	var duration time.Duration
+	for _, testFunctionSummary := range(summary.summaries) {
+		duration += testFunctionSummary.Duration()
+	}
+
+	return duration
+}
+
 type TestFunctionSummary struct {
 	TestFunction *TestFunction
 	errors []TestError
+	output []string
+	duration time.Duration
+	skipped bool
+	expectedFailure bool
+	reason string
 }
 
 func (summary *TestFunctionSummary) Errors() []TestError {
 	return summary.errors
 }
 
+// Output returns the messages logged by the test function, e.g. using kurtestosis.debug
+func (summary *TestFunctionSummary) Output() []string {
+	return summary.output
+}
+
+// ErrorMessages returns the collected errors formatted as human-readable strings
+func (summary *TestFunctionSummary) ErrorMessages() []string {
+	messages := make([]string, len(summary.errors))
+	for i, testError := range summary.errors {
+		// Interpretation errors come with escaped newlines so we turn them into real ones
+		messages[i] = strings.ReplaceAll(fmt.Sprint(testError...), "\\n", "\n")
+	}
+
+	return messages
+}
+
+func (summary *TestFunctionSummary) Duration() time.Duration {
+	return summary.duration
+}
+
+// Status determines the outcome of the test function
+//
+// Errors take precedence over skipping so that e.g. a failing teardown is not hidden by a skipped test
+func (summary *TestFunctionSummary) Status() TestStatus {
+	failed := len(summary.errors) > 0
+	switch {
+	case summary.skipped && !failed:
+		return TestStatusSkip
+	case summary.expectedFailure && failed:
+		return TestStatusXFail
+	case summary.expectedFailure:
+		return TestStatusXPass
+	case failed:
+		return TestStatusFail
+	default:
+		return TestStatusPass
+	}
+}
+
+// Reason returns the reason given when skipping the test function or marking it as expected to fail
+func (summary *TestFunctionSummary) Reason() string {
+	return summary.reason
+}
+
+// Success returns false if the test function failed unexpectedly
 func (summary *TestFunctionSummary) Success() bool {
-	return len(summary.errors) == 0
+	return summary.Status() != TestStatusFail
 }
 
+// TestReporter collects the outcome of a test function
+//
+// A test that could not be stopped in time keeps reporting from the background while the runner reads its summary,
+// so the reporter is synchronized and can be abandoned
 type TestReporter struct {
 	TestFunction *TestFunction
+	mutex sync.Mutex
+	abandoned bool
 	errors []TestError
+	output []string
+	startTime time.Time
+	skipped bool
+	// The error that stopped the test function when it was skipped using kurtestosis.skip
+	skipErr error
+	expectedFailure bool
+	reason string
 }
 
 func (reporter *TestReporter) Error(args ...interface{}) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.abandoned {
+		return
+	}
+
+	reporter.errors = append(reporter.errors, args)
+}
+
+// Abandon fails the test function with a final error and ignores anything reported afterwards
+//
+// This is used for tests that could not be stopped in time, the interpretation still running in the background
+// must not change the outcome of the test once it has been reported
+func (reporter *TestReporter) Abandon(args ...interface{}) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
 	reporter.errors = append(reporter.errors, args)
+	reporter.abandoned = true
+}
+
+// Failed returns true if the test function has reported any errors
+func (reporter *TestReporter) Failed() bool {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	return len(reporter.errors) > 0
+}
+
+// Log records a message produced by the test function
+//
+// The messages are collected rather than printed right away so that the test output stays grouped
+func (reporter *TestReporter) Log(message string) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.abandoned {
+		return
+	}
+
+	reporter.output = append(reporter.output, message)
+}
+
+// Skip marks the test function as skipped
+func (reporter *TestReporter) Skip(reason string) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.abandoned {
+		return
+	}
+
+	reporter.skipped = true
+	reporter.reason = reason
+}
+
+// SkipWithError marks the test function as skipped by err, which is returned to stop it
+//
+// Starlark has no other way of stopping the execution, the runner uses IsSkipError
+// to tell this error apart from the ones that make the test function fail
+func (reporter *TestReporter) SkipWithError(reason string, err error) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.abandoned {
+		return
+	}
+
+	reporter.skipped = true
+	reporter.skipErr = err
+	reporter.reason = reason
+}
+
+// IsSkipError returns true if err is (or wraps) the error that stopped the skipped test function
+func (reporter *TestReporter) IsSkipError(err error) bool {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	return reporter.skipErr != nil && errors.Is(err, reporter.skipErr)
+}
+
+// Skipped returns true if the test function has been marked as skipped
+func (reporter *TestReporter) Skipped() bool {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	return reporter.skipped
+}
+
+// ExpectFailure marks the test function as expected to fail
+func (reporter *TestReporter) ExpectFailure(reason string) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.abandoned {
+		return
+	}
+
+	reporter.expectedFailure = true
+	reporter.reason = reason
 }
 
 func (reporter *TestReporter) Summary() *TestFunctionSummary {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
 	return &TestFunctionSummary{
 		TestFunction: reporter.TestFunction,
 		errors: reporter.errors,
+		output: reporter.output,
+		duration: time.Since(reporter.startTime),
+		skipped: reporter.skipped,
+		expectedFailure: reporter.expectedFailure,
+		reason: reporter.reason,
 	}
 }
 
+// NewTestReporter creates a reporter for a test function, taking the markers of the test function into account
 func NewTestReporter(testFunction *TestFunction) *TestReporter {
-	return &TestReporter{
+	reporter := &TestReporter{
+		TestFunction: testFunction,
+		startTime: time.Now(),
+	}
+
+	if testFunction.Markers.Skip {
+		reporter.Skip(testFunction.Markers.Reason)
+	}
+
+	if testFunction.Markers.XFail {
+		reporter.ExpectFailure(testFunction.Markers.Reason)
+	}
+
+	return reporter
+}
+
+// NewErrorTestFunctionSummary creates the summary of a test function that could not be run
+//
+// The markers of the test function are ignored, a test function that could not be run always fails
+func NewErrorTestFunctionSummary(testFunction *TestFunction, err error) *TestFunctionSummary {
+	return &TestFunctionSummary{
 		TestFunction: testFunction,
+		errors: []TestError{{err}},
 	}
 }
 
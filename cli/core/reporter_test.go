@@ -0,0 +1,66 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTestReporterAbandon(t *testing.T) {
+	reporter := NewTestReporter(&TestFunction{TestFile: &TestFile{Path: "main_test.star"}, Name: "test_stuck"})
+	reporter.Log("before")
+	reporter.Abandon("test timed out")
+
+	// An interpretation running in the background must not change the outcome anymore
+	reporter.Error("late error")
+	reporter.Log("after")
+	reporter.Skip("late skip")
+	reporter.ExpectFailure("late xfail")
+
+	summary := reporter.Summary()
+	if summary.Status() != TestStatusFail {
+		t.Errorf("expected the abandoned test to fail, got %s", summary.Status())
+	}
+
+	if messages := summary.ErrorMessages(); len(messages) != 1 || messages[0] != "test timed out" {
+		t.Errorf("expected only the abandon error, got %q", messages)
+	}
+
+	if output := summary.Output(); len(output) != 1 || output[0] != "before" {
+		t.Errorf("expected only the output logged before abandoning, got %q", output)
+	}
+}
+
+func TestTestReporterIsSkipError(t *testing.T) {
+	reporter := NewTestReporter(&TestFunction{TestFile: &TestFile{Path: "main_test.star"}, Name: "test_skip"})
+
+	skipErr := fmt.Errorf("kurtestosis.skip: %w", ErrTestSkipped)
+	if reporter.IsSkipError(skipErr) {
+		t.Errorf("expected no skip error before the test function is skipped")
+	}
+
+	reporter.SkipWithError("not supported yet", skipErr)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "skip error", err: skipErr, expected: true},
+		{name: "wrapped skip error", err: fmt.Errorf("in test_skip: %w", skipErr), expected: true},
+		{name: "error with the same message", err: errors.New(skipErr.Error()), expected: false},
+		{name: "other error", err: errors.New("assertion failed"), expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if reporter.IsSkipError(test.err) != test.expected {
+				t.Errorf("expected IsSkipError(%v) to be %v", test.err, test.expected)
+			}
+		})
+	}
+
+	if reporter.Summary().Status() != TestStatusSkip {
+		t.Errorf("expected the test function to be skipped")
+	}
+}
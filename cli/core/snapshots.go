@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the directory, next to the test file, in which the snapshots are stored
+const SnapshotsDirName = "__snapshots__"
+
+const snapshotFileExtension = ".snap"
+
+// Snapshot names end up in file names so they are kept simple
+var snapshotNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Snapshots compares the values produced by a test function with the ones stored in its snapshot files
+//
+// Every test function gets its own snapshot files:
+//
+//	__snapshots__/<test file>/<test function>.snap         the plan generated by the test function
+//	__snapshots__/<test file>/<test function>.<name>.snap  a named snapshot taken by the test function
+type Snapshots struct {
+	TestFunction *TestFunction
+	// Whether to write the snapshot files instead of comparing against them
+	update bool
+	// Names of the snapshots taken so far, a test function can only take a snapshot with a given name once
+	names map[string]bool
+}
+
+func NewSnapshots(testFunction *TestFunction, update bool) *Snapshots {
+	return &Snapshots{
+		TestFunction: testFunction,
+		update:       update,
+		names:        map[string]bool{},
+	}
+}
+
+// Match compares a named snapshot with its snapshot file
+//
+// A missing snapshot file is an error unless the snapshots are being updated
+func (snapshots *Snapshots) Match(name string, actual string) error {
+	if !snapshotNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name '%s', only letters, digits, underscores and dashes are allowed", name)
+	}
+
+	if snapshots.names[name] {
+		return fmt.Errorf("snapshot %s has already been taken by this test", name)
+	}
+	snapshots.names[name] = true
+
+	return snapshots.match(snapshots.path(name), actual, false)
+}
+
+// MatchPlan compares the plan generated by the test function with its snapshot file
+//
+// A missing plan snapshot file is written on the first run, unless the test function generated an empty plan
+func (snapshots *Snapshots) MatchPlan(actual string) error {
+	path := snapshots.path("")
+	if actual == "" {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+
+	return snapshots.match(path, actual, true)
+}
+
+// Compares a snapshot file with the actual value
+//
+// A missing snapshot file is either an error or, if writeMissing is set, written for the next runs to compare against
+func (snapshots *Snapshots) match(path string, actual string, writeMissing bool) error {
+	relativePath, err := filepath.Rel(snapshots.TestFunction.TestFile.Project.Path, path)
+	if err != nil {
+		relativePath = path
+	}
+
+	if snapshots.update {
+		return writeSnapshot(path, actual)
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !writeMissing {
+			return fmt.Errorf("snapshot %s does not exist, run with --update-snapshots to create it", relativePath)
+		}
+
+		logrus.Infof("Snapshot %s does not exist, writing it", relativePath)
+
+		return writeSnapshot(path, actual)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot %s: %w", relativePath, err)
+	}
+
+	if string(expected) == actual {
+		return nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitSnapshotLines(string(expected)),
+		B:        splitSnapshotLines(actual),
+		FromFile: relativePath,
+		ToFile:   "actual",
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to diff snapshot %s: %w", relativePath, err)
+	}
+
+	return fmt.Errorf("snapshot %s does not match, run with --update-snapshots to update it:\n%s", relativePath, diff)
+}
+
+// Returns the path of a snapshot file, an empty name stands for the plan snapshot
+func (snapshots *Snapshots) path(name string) string {
+	testFile := snapshots.TestFunction.TestFile
+	fileName := snapshots.TestFunction.DisplayName()
+	if name != "" {
+		fileName += "." + name
+	}
+
+	return filepath.Join(
+		testFile.Project.Path,
+		filepath.Dir(testFile.Path),
+		SnapshotsDirName,
+		filepath.Base(testFile.Path),
+		fileName+snapshotFileExtension,
+	)
+}
+
+// Splits a snapshot into lines, the trailing newline does not start a new line
+func splitSnapshotLines(snapshot string) []string {
+	return difflib.SplitLines(strings.TrimSuffix(snapshot, "\n"))
+}
+
+func writeSnapshot(path string, content string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot directory %s: %w", filepath.Dir(path), err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", path, err)
+	}
+
+	return nil
+}
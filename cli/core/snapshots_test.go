@@ -0,0 +1,60 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotsMissing(t *testing.T) {
+	projectPath := t.TempDir()
+	testFunction := &TestFunction{
+		TestFile: &TestFile{Project: &KurtestosisProject{Path: projectPath}, Path: "test/main_test.star"},
+		Name:     "test_main",
+	}
+	snapshotsDirPath := filepath.Join(projectPath, "test", SnapshotsDirName, "main_test.star")
+
+	t.Run("plan snapshot", func(t *testing.T) {
+		// A missing plan snapshot is written on the first run and compared against from then on
+		err := NewSnapshots(testFunction, false).MatchPlan("add_service()\n")
+		if err != nil {
+			t.Fatalf("expected the missing plan snapshot to be written, got %v", err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(snapshotsDirPath, "test_main.snap"))
+		if err != nil {
+			t.Fatalf("failed to read plan snapshot: %v", err)
+		}
+
+		if string(content) != "add_service()\n" {
+			t.Errorf("expected the plan to be written to the snapshot, got %q", content)
+		}
+
+		err = NewSnapshots(testFunction, false).MatchPlan("remove_service()\n")
+		if err == nil || !strings.Contains(err.Error(), "does not match, run with --update-snapshots to update it") {
+			t.Errorf("expected the written plan snapshot to be compared against, got %v", err)
+		}
+	})
+
+	t.Run("empty plan", func(t *testing.T) {
+		emptyTestFunction := &TestFunction{TestFile: testFunction.TestFile, Name: "test_empty"}
+
+		err := NewSnapshots(emptyTestFunction, false).MatchPlan("")
+		if err != nil {
+			t.Fatalf("expected an empty plan to pass, got %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(snapshotsDirPath, "test_empty.snap")); !os.IsNotExist(err) {
+			t.Errorf("expected no snapshot to be written for an empty plan")
+		}
+	})
+
+	t.Run("named snapshot", func(t *testing.T) {
+		err := NewSnapshots(testFunction, false).Match("genesis", "{}")
+		expectedMessage := "snapshot " + filepath.Join("test", SnapshotsDirName, "main_test.star", "test_main.genesis.snap") + " does not exist, run with --update-snapshots to create it"
+		if err == nil || err.Error() != expectedMessage {
+			t.Errorf("expected %q, got %v", expectedMessage, err)
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/lib/shared_utils"
+)
+
+// Name of the directory, in the project root, in which the remote packages are vendored
+//
+// Packages are vendored under their repository paths, e.g. vendor/ethpandaops/ethereum-package
+const VendorDirName = "vendor"
+
+// Name of the file, in the project root, that pins the vendored packages to their commits
+const LockfileName = "kurtestosis.lock"
+
+// Lockfile lists the remote packages vendored into a project
+type Lockfile struct {
+	Packages []*LockedPackage `json:"packages"`
+}
+
+// LockedPackage is a remote package vendored into a project
+type LockedPackage struct {
+	// Name of the package, e.g. github.com/ethpandaops/ethereum-package
+	Name string `json:"name"`
+	// Tag, branch or commit the package was requested at, empty for the default branch
+	Version string `json:"version,omitempty"`
+	// Commit the package was resolved to
+	Commit string `json:"commit"`
+	// Hash of the vendored files, used to make sure the vendor directory has not changed since the package was vendored
+	Hash string `json:"hash"`
+}
+
+// Prefix of the package hashes, identifying the hashing algorithm
+const packageHashPrefix = "sha256:"
+
+// VendorDirPath returns the path of the directory the remote packages are vendored to
+func (project *KurtestosisProject) VendorDirPath() string {
+	return filepath.Join(project.Path, VendorDirName)
+}
+
+// LockfilePath returns the path of the lockfile of the project
+func (project *KurtestosisProject) LockfilePath() string {
+	return filepath.Join(project.Path, LockfileName)
+}
+
+// ReadLockfile reads the lockfile of a project, a project that has not vendored any packages gets an empty lockfile
+func ReadLockfile(project *KurtestosisProject) (*Lockfile, error) {
+	content, err := os.ReadFile(project.LockfilePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Lockfile{Packages: []*LockedPackage{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", project.LockfilePath(), err)
+	}
+
+	lockfile := &Lockfile{}
+	err = json.Unmarshal(content, lockfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", project.LockfilePath(), err)
+	}
+
+	return lockfile, nil
+}
+
+// WriteLockfile writes the lockfile of a project, with the packages sorted by name
+func WriteLockfile(project *KurtestosisProject, lockfile *Lockfile) error {
+	sort.Slice(lockfile.Packages, func(i, j int) bool {
+		return lockfile.Packages[i].Name < lockfile.Packages[j].Name
+	})
+
+	content, err := json.MarshalIndent(lockfile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize lockfile: %w", err)
+	}
+
+	err = os.WriteFile(project.LockfilePath(), append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", project.LockfilePath(), err)
+	}
+
+	return nil
+}
+
+// FindPackage returns the locked package with the given name, or nil if the package is not locked
+func (lockfile *Lockfile) FindPackage(name string) *LockedPackage {
+	for _, lockedPackage := range lockfile.Packages {
+		if lockedPackage.Name == name {
+			return lockedPackage
+		}
+	}
+
+	return nil
+}
+
+// Matches returns true if the package was requested at version, or if version is a prefix of the commit it was resolved to
+//
+// An empty version (i.e. the default branch) matches a package requested without a version
+func (lockedPackage *LockedPackage) Matches(version string) bool {
+	if version == lockedPackage.Version {
+		return true
+	}
+
+	return version != "" && strings.HasPrefix(lockedPackage.Commit, version)
+}
+
+// VerifyVendorDir makes sure that the vendor directory of a project contains exactly the packages pinned in its lockfile
+//
+// Any difference means that either the lockfile or the vendor directory has been changed by hand,
+// in which case the test runs would not be reproducible
+func VerifyVendorDir(project *KurtestosisProject, lockfile *Lockfile) error {
+	vendoredPackages, err := listVendoredPackages(project)
+	if err != nil {
+		return err
+	}
+
+	vendored := map[string]bool{}
+	for _, name := range vendoredPackages {
+		vendored[name] = true
+
+		lockedPackage := lockfile.FindPackage(name)
+		if lockedPackage == nil {
+			return fmt.Errorf("package %s is vendored but not pinned in %s, run kurtestosis vendor to update the vendor directory", name, LockfileName)
+		}
+
+		hash, err := HashPackage(filepath.Join(project.VendorDirPath(), strings.TrimPrefix(name, shared_utils.GithubDomainPrefix+"/")))
+		if err != nil {
+			return err
+		}
+
+		if hash != lockedPackage.Hash {
+			return fmt.Errorf("vendored package %s does not match %s, expected hash %s but got %s. Run kurtestosis vendor to update the vendor directory", name, LockfileName, lockedPackage.Hash, hash)
+		}
+	}
+
+	for _, lockedPackage := range lockfile.Packages {
+		if !vendored[lockedPackage.Name] {
+			return fmt.Errorf("package %s is pinned in %s but missing from the vendor directory, run kurtestosis vendor to update the vendor directory", lockedPackage.Name, LockfileName)
+		}
+	}
+
+	return nil
+}
+
+// Lists the names of the packages in the vendor directory
+//
+// Packages are vendored under their repository paths, so they are the directories two levels deep
+func listVendoredPackages(project *KurtestosisProject) ([]string, error) {
+	owners, err := os.ReadDir(project.VendorDirPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vendor directory %s: %w", project.VendorDirPath(), err)
+	}
+
+	names := []string{}
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+
+		repositories, err := os.ReadDir(filepath.Join(project.VendorDirPath(), owner.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vendor directory %s: %w", project.VendorDirPath(), err)
+		}
+
+		for _, repository := range repositories {
+			if repository.IsDir() {
+				names = append(names, shared_utils.GithubDomainPrefix+"/"+owner.Name()+"/"+repository.Name())
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// HashPackage hashes the paths and contents of all the files of a vendored package
+func HashPackage(packagePath string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(packagePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(packagePath, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		contentHash := sha256.Sum256(content)
+		fmt.Fprintf(hash, "%s\x00%s\n", filepath.ToSlash(relativePath), hex.EncodeToString(contentHash[:]))
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash package %s: %w", packagePath, err)
+	}
+
+	return packageHashPrefix + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopyPackage copies a cloned package to the vendor directory, without its git metadata
+func CopyPackage(sourcePath string, destinationPath string) error {
+	return filepath.WalkDir(sourcePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Submodules have a .git file instead of a directory
+		if entry.Name() == ".git" {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(sourcePath, path)
+		if err != nil {
+			return err
+		}
+
+		targetPath := filepath.Join(destinationPath, relativePath)
+		if entry.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+
+		// Symlinks and other special files are not needed to load the package
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		return copyFile(path, targetPath)
+	})
+}
+
+func copyFile(sourcePath string, destinationPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.Create(destinationPath)
+	if err != nil {
+		return err
+	}
+	defer destination.Close()
+
+	_, err = io.Copy(destination, source)
+
+	return err
+}
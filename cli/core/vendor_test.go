@@ -0,0 +1,375 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLockedPackageMatches(t *testing.T) {
+	tests := []struct {
+		name          string
+		lockedPackage LockedPackage
+		version       string
+		expected      bool
+	}{
+		{
+			name:          "default branch",
+			lockedPackage: LockedPackage{Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "",
+			expected:      true,
+		},
+		{
+			name:          "same version",
+			lockedPackage: LockedPackage{Version: "v1.2", Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "v1.2",
+			expected:      true,
+		},
+		{
+			name:          "different version",
+			lockedPackage: LockedPackage{Version: "v1.2", Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "v1.3",
+			expected:      false,
+		},
+		{
+			name:          "version requested for a package vendored from the default branch",
+			lockedPackage: LockedPackage{Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "v1.2",
+			expected:      false,
+		},
+		{
+			name:          "default branch requested for a package vendored at a version",
+			lockedPackage: LockedPackage{Version: "v1.2", Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "",
+			expected:      false,
+		},
+		{
+			name:          "full commit",
+			lockedPackage: LockedPackage{Version: "main", Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "e78cedd496c8ed449688c78d37bf091b141a3186",
+			expected:      true,
+		},
+		{
+			name:          "short commit",
+			lockedPackage: LockedPackage{Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "e78cedd",
+			expected:      true,
+		},
+		{
+			name:          "different commit",
+			lockedPackage: LockedPackage{Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+			version:       "f00ba4",
+			expected:      false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.lockedPackage.Matches(test.version) != test.expected {
+				t.Errorf("expected Matches(%q) to be %v", test.version, test.expected)
+			}
+		})
+	}
+}
+
+func TestHashPackage(t *testing.T) {
+	files := map[string]string{
+		"kurtosis.yml":     "name: github.com/org/repo\n",
+		"main.star":        "def run(plan):\n    return 1\n",
+		"lib/helpers.star": "a = 1\n",
+	}
+
+	tests := []struct {
+		name          string
+		change        func(t *testing.T, packagePath string)
+		expectChanged bool
+	}{
+		{
+			name:          "unchanged",
+			change:        func(t *testing.T, packagePath string) {},
+			expectChanged: false,
+		},
+		{
+			name: "modified file",
+			change: func(t *testing.T, packagePath string) {
+				writeTestFile(t, filepath.Join(packagePath, "main.star"), "def run(plan):\n    return 2\n")
+			},
+			expectChanged: true,
+		},
+		{
+			name: "added file",
+			change: func(t *testing.T, packagePath string) {
+				writeTestFile(t, filepath.Join(packagePath, "lib/other.star"), "")
+			},
+			expectChanged: true,
+		},
+		{
+			name: "removed file",
+			change: func(t *testing.T, packagePath string) {
+				err := os.Remove(filepath.Join(packagePath, "lib/helpers.star"))
+				if err != nil {
+					t.Fatalf("failed to remove file: %v", err)
+				}
+			},
+			expectChanged: true,
+		},
+		{
+			name: "renamed file",
+			change: func(t *testing.T, packagePath string) {
+				err := os.Rename(filepath.Join(packagePath, "lib/helpers.star"), filepath.Join(packagePath, "lib/renamed.star"))
+				if err != nil {
+					t.Fatalf("failed to rename file: %v", err)
+				}
+			},
+			expectChanged: true,
+		},
+		{
+			name: "added empty directory",
+			change: func(t *testing.T, packagePath string) {
+				err := os.MkdirAll(filepath.Join(packagePath, "empty"), 0755)
+				if err != nil {
+					t.Fatalf("failed to create directory: %v", err)
+				}
+			},
+			expectChanged: false,
+		},
+	}
+
+	// The hash only depends on the files of the package, not on where the package is
+	referencePath := filepath.Join(t.TempDir(), "reference")
+	for path, content := range files {
+		writeTestFile(t, filepath.Join(referencePath, path), content)
+	}
+
+	referenceHash, err := HashPackage(referencePath)
+	if err != nil {
+		t.Fatalf("failed to hash package: %v", err)
+	}
+
+	if !strings.HasPrefix(referenceHash, packageHashPrefix) {
+		t.Errorf("expected hash %s to start with %s", referenceHash, packageHashPrefix)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packagePath := filepath.Join(t.TempDir(), "package")
+			for path, content := range files {
+				writeTestFile(t, filepath.Join(packagePath, path), content)
+			}
+
+			test.change(t, packagePath)
+
+			hash, err := HashPackage(packagePath)
+			if err != nil {
+				t.Fatalf("failed to hash package: %v", err)
+			}
+
+			if (hash != referenceHash) != test.expectChanged {
+				t.Errorf("expected the hash to change: %v, got %s and %s", test.expectChanged, referenceHash, hash)
+			}
+		})
+	}
+}
+
+func TestCopyPackage(t *testing.T) {
+	sourcePath := t.TempDir()
+	writeTestFile(t, filepath.Join(sourcePath, "main.star"), "a = 1\n")
+	writeTestFile(t, filepath.Join(sourcePath, "lib/helpers.star"), "b = 2\n")
+	writeTestFile(t, filepath.Join(sourcePath, ".git/HEAD"), "ref: refs/heads/main\n")
+	writeTestFile(t, filepath.Join(sourcePath, "submodule/.git"), "gitdir: ../.git/modules/submodule\n")
+
+	destinationPath := filepath.Join(t.TempDir(), "vendor", "org", "repo")
+	err := CopyPackage(sourcePath, destinationPath)
+	if err != nil {
+		t.Fatalf("failed to copy package: %v", err)
+	}
+
+	copied := []string{}
+	err = filepath.WalkDir(destinationPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(destinationPath, path)
+		copied = append(copied, filepath.ToSlash(relativePath))
+
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to list copied files: %v", err)
+	}
+
+	expected := []string{"lib/helpers.star", "main.star"}
+	if !reflect.DeepEqual(copied, expected) {
+		t.Errorf("expected copied files %q, got %q", expected, copied)
+	}
+}
+
+func TestVerifyVendorDir(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(t *testing.T, project *KurtestosisProject) *Lockfile
+		expectedError string
+	}{
+		{
+			name: "no vendor directory",
+			setup: func(t *testing.T, project *KurtestosisProject) *Lockfile {
+				return &Lockfile{Packages: []*LockedPackage{}}
+			},
+		},
+		{
+			name: "vendored packages match",
+			setup: func(t *testing.T, project *KurtestosisProject) *Lockfile {
+				return &Lockfile{Packages: []*LockedPackage{
+					vendorTestPackage(t, project, "org/first"),
+					vendorTestPackage(t, project, "org/second"),
+				}}
+			},
+		},
+		{
+			name: "vendored package modified",
+			setup: func(t *testing.T, project *KurtestosisProject) *Lockfile {
+				lockedPackage := vendorTestPackage(t, project, "org/first")
+				writeTestFile(t, filepath.Join(project.VendorDirPath(), "org/first/main.star"), "modified = True\n")
+
+				return &Lockfile{Packages: []*LockedPackage{lockedPackage}}
+			},
+			expectedError: "vendored package github.com/org/first does not match kurtestosis.lock",
+		},
+		{
+			name: "vendored package not pinned",
+			setup: func(t *testing.T, project *KurtestosisProject) *Lockfile {
+				vendorTestPackage(t, project, "org/first")
+
+				return &Lockfile{Packages: []*LockedPackage{}}
+			},
+			expectedError: "package github.com/org/first is vendored but not pinned in kurtestosis.lock",
+		},
+		{
+			name: "pinned package missing",
+			setup: func(t *testing.T, project *KurtestosisProject) *Lockfile {
+				return &Lockfile{Packages: []*LockedPackage{
+					{Name: "github.com/org/first", Commit: "e78cedd496c8ed449688c78d37bf091b141a3186"},
+				}}
+			},
+			expectedError: "package github.com/org/first is pinned in kurtestosis.lock but missing from the vendor directory",
+		},
+		{
+			name: "files next to the vendored packages",
+			setup: func(t *testing.T, project *KurtestosisProject) *Lockfile {
+				lockedPackage := vendorTestPackage(t, project, "org/first")
+				writeTestFile(t, filepath.Join(project.VendorDirPath(), "README.md"), "")
+				writeTestFile(t, filepath.Join(project.VendorDirPath(), "org/README.md"), "")
+
+				return &Lockfile{Packages: []*LockedPackage{lockedPackage}}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project := &KurtestosisProject{Path: t.TempDir()}
+			lockfile := test.setup(t, project)
+
+			err := VerifyVendorDir(project, lockfile)
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected the vendor directory to match, got %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("expected an error containing %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLockfile(t *testing.T) {
+	project := &KurtestosisProject{Path: t.TempDir()}
+
+	// A project that has not vendored any packages has an empty lockfile
+	lockfile, err := ReadLockfile(project)
+	if err != nil {
+		t.Fatalf("failed to read missing lockfile: %v", err)
+	}
+	if len(lockfile.Packages) != 0 {
+		t.Errorf("expected an empty lockfile, got %+v", lockfile)
+	}
+
+	lockfile = &Lockfile{Packages: []*LockedPackage{
+		{Name: "github.com/org/second", Commit: "2222222", Hash: "sha256:2"},
+		{Name: "github.com/org/first", Version: "v1.2", Commit: "1111111", Hash: "sha256:1"},
+	}}
+	err = WriteLockfile(project, lockfile)
+	if err != nil {
+		t.Fatalf("failed to write lockfile: %v", err)
+	}
+
+	readLockfile, err := ReadLockfile(project)
+	if err != nil {
+		t.Fatalf("failed to read lockfile: %v", err)
+	}
+
+	// The packages are written sorted by name
+	expected := &Lockfile{Packages: []*LockedPackage{
+		{Name: "github.com/org/first", Version: "v1.2", Commit: "1111111", Hash: "sha256:1"},
+		{Name: "github.com/org/second", Commit: "2222222", Hash: "sha256:2"},
+	}}
+	if !reflect.DeepEqual(readLockfile, expected) {
+		t.Errorf("expected lockfile %+v, got %+v", expected, readLockfile)
+	}
+
+	if readLockfile.FindPackage("github.com/org/second") != readLockfile.Packages[1] {
+		t.Errorf("expected to find github.com/org/second")
+	}
+	if readLockfile.FindPackage("github.com/org/missing") != nil {
+		t.Errorf("expected not to find github.com/org/missing")
+	}
+}
+
+func TestListMatchingTestFilesSkipsVendoredPackages(t *testing.T) {
+	projectPath := t.TempDir()
+	writeTestFile(t, filepath.Join(projectPath, "main_test.star"), "")
+	writeTestFile(t, filepath.Join(projectPath, VendorDirName, "org/repo/main_test.star"), "")
+	writeTestFile(t, filepath.Join(projectPath, "vendored_test.star"), "")
+
+	testFiles, err := ListMatchingTestFiles(&KurtestosisProject{Path: projectPath}, "**/*_test.star")
+	if err != nil {
+		t.Fatalf("failed to list test files: %v", err)
+	}
+
+	paths := []string{}
+	for _, testFile := range testFiles {
+		paths = append(paths, testFile.Path)
+	}
+
+	expected := []string{"main_test.star", "vendored_test.star"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected test files %q, got %q", expected, paths)
+	}
+}
+
+// Writes a package into the vendor directory of a project and returns its lockfile entry
+func vendorTestPackage(t *testing.T, project *KurtestosisProject, repositoryPath string) *LockedPackage {
+	t.Helper()
+
+	packagePath := filepath.Join(project.VendorDirPath(), repositoryPath)
+	writeTestFile(t, filepath.Join(packagePath, "kurtosis.yml"), "name: github.com/"+repositoryPath+"\n")
+	writeTestFile(t, filepath.Join(packagePath, "main.star"), "def run(plan):\n    return 1\n")
+
+	hash, err := HashPackage(packagePath)
+	if err != nil {
+		t.Fatalf("failed to hash package: %v", err)
+	}
+
+	return &LockedPackage{
+		Name:   "github.com/" + repositoryPath,
+		Commit: "e78cedd496c8ed449688c78d37bf091b141a3186",
+		Hash:   hash,
+	}
+}
@@ -89,7 +89,7 @@ func CreateStarlarkValueSerde() *kurtosis_types.StarlarkValueSerde {
 		OnMaxSteps: nil,
 		Steps:      0,
 	}
-	starlarkEnv := startosis_engine.Predeclared()
+	starlarkEnv := Predeclared()
 	builtins := startosis_engine.KurtosisTypeConstructors()
 	for _, builtin := range builtins {
 		starlarkEnv[builtin.Name()] = builtin
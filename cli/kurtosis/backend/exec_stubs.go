@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/exec_result"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service"
+)
+
+// ExecStub is a canned result of a command executed in a service
+type ExecStub struct {
+	ServiceName    service.ServiceName
+	CommandMatcher string
+	ExitCode       int32
+	Output         string
+
+	commandRegexp *regexp.Regexp
+}
+
+// NewExecStub creates a stub for commands matching commandMatcher regular expression
+//
+// The command is matched as a single string with its arguments separated by spaces
+// and the regular expression needs to match the whole command
+func NewExecStub(serviceName service.ServiceName, commandMatcher string, exitCode int32, output string) (*ExecStub, error) {
+	commandRegexp, err := regexp.Compile("^(?:" + commandMatcher + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid command matcher %s: %w", commandMatcher, err)
+	}
+
+	return &ExecStub{
+		ServiceName:    serviceName,
+		CommandMatcher: commandMatcher,
+		ExitCode:       exitCode,
+		Output:         output,
+		commandRegexp:  commandRegexp,
+	}, nil
+}
+
+func (stub *ExecStub) Matches(serviceName service.ServiceName, command []string) bool {
+	return stub.ServiceName == serviceName && stub.commandRegexp.MatchString(strings.Join(command, " "))
+}
+
+func (stub *ExecStub) String() string {
+	return fmt.Sprintf("%s: %s", stub.ServiceName, stub.CommandMatcher)
+}
+
+// StubExec registers a canned result for commands executed in a service
+//
+// If multiple stubs match a command, the one registered last is used
+func (network *KurtestosisServiceNetwork) StubExec(stub *ExecStub) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.execStubs = append(network.execStubs, stub)
+}
+
+func (network *KurtestosisServiceNetwork) runExecUnlocked(serviceIdentifier string, command []string) (*service.ServiceRegistration, *exec_result.ExecResult, error) {
+	registration, err := network.getRegistrationUnlocked(serviceIdentifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := len(network.execStubs) - 1; i >= 0; i-- {
+		stub := network.execStubs[i]
+		if stub.Matches(registration.GetName(), command) {
+			return registration, exec_result.NewExecResult(stub.ExitCode, stub.Output), nil
+		}
+	}
+
+	commandStr := strings.Join(command, " ")
+	if len(network.execStubs) == 0 {
+		return registration, nil, fmt.Errorf("no exec stub matches command '%s' in service %s, no exec stubs have been registered", commandStr, registration.GetName())
+	}
+
+	registeredStubs := make([]string, len(network.execStubs))
+	for i, stub := range network.execStubs {
+		registeredStubs[i] = "\t" + stub.String()
+	}
+
+	return registration, nil, fmt.Errorf("no exec stub matches command '%s' in service %s, registered exec stubs:\n%s", commandStr, registration.GetName(), strings.Join(registeredStubs, "\n"))
+}
@@ -0,0 +1,255 @@
+package backend
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/service_network/render_templates"
+	"github.com/kurtosis-tech/kurtosis/core/server/commons/enclave_data_directory"
+)
+
+const (
+	// Prefix of the names given to files artifacts that were not named explicitly
+	filesArtifactNamePrefix = "files-artifact-"
+
+	tempDirForRenderedTemplatesPattern = "kurtestosis-rendered-templates-"
+
+	// What go templates render in place of missing map keys
+	missingTemplateValue = "<no value>"
+)
+
+// FilesArtifact is a files artifact held in memory
+type FilesArtifact struct {
+	UUID enclave_data_directory.FilesArtifactUUID
+	Name string
+	Md5  []byte
+	// Contents of the files in the artifact, keyed by their path relative to the artifact root
+	Files map[string]string
+	// Whether the files were rendered from templates using plan.render_templates
+	Rendered bool
+}
+
+// Paths returns the paths of the files in the artifact in alphabetical order
+func (artifact *FilesArtifact) Paths() []string {
+	paths := make([]string, 0, len(artifact.Files))
+	for path := range artifact.Files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func (network *KurtestosisServiceNetwork) RenderTemplates(templatesAndDataByDestinationRelFilepath map[string]*render_templates.TemplateData, artifactName string) (enclave_data_directory.FilesArtifactUUID, error) {
+	// The templates are rendered to disk by kurtosis so we collect the rendered files from a temporary directory
+	tempDir, err := os.MkdirTemp("", tempDirForRenderedTemplatesPattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary directory for rendered templates: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for destinationRelFilepath, templateAndData := range templatesAndDataByDestinationRelFilepath {
+		if err := templateAndData.RenderToFile(filepath.Join(tempDir, destinationRelFilepath)); err != nil {
+			return "", fmt.Errorf("failed to render template for file %s: %w", destinationRelFilepath, err)
+		}
+	}
+
+	files, err := readFilesFromDir(tempDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Go templates render missing map keys as <no value> instead of failing, which is easy to miss.
+	// The templates are parsed by kurtosis so they cannot be made to fail on missing keys, we fail on their output instead
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if strings.Contains(files[path], missingTemplateValue) {
+			return "", fmt.Errorf("rendered template %s in files artifact %s contains %s, the template uses a key that is missing from its data", path, artifactName, missingTemplateValue)
+		}
+	}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	artifactUUID := network.storeFilesArtifactUnlocked(artifactName, files, nil)
+	network.filesArtifacts[artifactName].Rendered = true
+
+	return artifactUUID, nil
+}
+
+func (network *KurtestosisServiceNetwork) UploadFilesArtifact(data io.Reader, contentMd5 []byte, artifactName string) (enclave_data_directory.FilesArtifactUUID, error) {
+	files, err := readFilesFromArchive(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read files artifact %s: %w", artifactName, err)
+	}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	if _, exists := network.filesArtifacts[artifactName]; exists {
+		return "", fmt.Errorf("files artifact %s already exists", artifactName)
+	}
+
+	return network.storeFilesArtifactUnlocked(artifactName, files, contentMd5), nil
+}
+
+func (network *KurtestosisServiceNetwork) GetFilesArtifactMd5(artifactName string) (enclave_data_directory.FilesArtifactUUID, []byte, bool, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	artifact, exists := network.filesArtifacts[artifactName]
+	if !exists {
+		return "", nil, false, nil
+	}
+
+	return artifact.UUID, artifact.Md5, true, nil
+}
+
+func (network *KurtestosisServiceNetwork) UpdateFilesArtifact(fileArtifactUuid enclave_data_directory.FilesArtifactUUID, updatedContent io.Reader, contentMd5 []byte) error {
+	files, err := readFilesFromArchive(updatedContent)
+	if err != nil {
+		return fmt.Errorf("failed to read files artifact %s: %w", fileArtifactUuid, err)
+	}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	for _, artifact := range network.filesArtifacts {
+		if artifact.UUID == fileArtifactUuid {
+			artifact.Files = files
+			artifact.Md5 = contentMd5
+			artifact.Rendered = false
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("files artifact %s does not exist", fileArtifactUuid)
+}
+
+// GetUniqueNameForFileArtifact returns a name that no other files artifact uses
+//
+// Unlike kurtosis, which picks random names, the names are numbered so that the tests are deterministic
+func (network *KurtestosisServiceNetwork) GetUniqueNameForFileArtifact() (string, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	for {
+		network.filesArtifactNameCounter++
+
+		name := fmt.Sprintf("%s%d", filesArtifactNamePrefix, network.filesArtifactNameCounter)
+		if _, exists := network.filesArtifacts[name]; !exists {
+			return name, nil
+		}
+	}
+}
+
+// GetFilesArtifact returns a files artifact by its name
+func (network *KurtestosisServiceNetwork) GetFilesArtifact(artifactName string) (*FilesArtifact, bool) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	artifact, exists := network.filesArtifacts[artifactName]
+
+	return artifact, exists
+}
+
+// Stores the files under an artifact name, replacing the files of an existing artifact with the same name
+func (network *KurtestosisServiceNetwork) storeFilesArtifactUnlocked(artifactName string, files map[string]string, contentMd5 []byte) enclave_data_directory.FilesArtifactUUID {
+	if artifact, exists := network.filesArtifacts[artifactName]; exists {
+		artifact.Files = files
+		artifact.Md5 = contentMd5
+		artifact.Rendered = false
+
+		return artifact.UUID
+	}
+
+	artifactUUID := enclave_data_directory.FilesArtifactUUID(fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s/%d", artifactName, len(network.filesArtifacts))))))
+	network.filesArtifacts[artifactName] = &FilesArtifact{
+		UUID:  artifactUUID,
+		Name:  artifactName,
+		Md5:   contentMd5,
+		Files: files,
+	}
+
+	return artifactUUID
+}
+
+// Reads the files from a gzipped tarball, the format kurtosis uses for files artifacts
+func readFilesFromArchive(data io.Reader) (map[string]string, error) {
+	gzipReader, err := gzip.NewReader(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	defer gzipReader.Close()
+
+	files := map[string]string{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %w", header.Name, err)
+		}
+
+		files[filepath.Clean(header.Name)] = string(content)
+	}
+
+	return files, nil
+}
+
+// Reads all the files in a directory, keyed by their path relative to the directory
+func readFilesFromDir(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		files[relativePath] = string(content)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files from %s: %w", dir, err)
+	}
+
+	return files, nil
+}
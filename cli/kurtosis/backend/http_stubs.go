@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service"
+)
+
+// HttpStub is a canned response to HTTP requests made to a service port
+type HttpStub struct {
+	ServiceName service.ServiceName
+	PortId      string
+	Method      string
+	Endpoint    string
+
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+}
+
+func (stub *HttpStub) Matches(request *HttpRequest) bool {
+	return stub.ServiceName == request.ServiceName && stub.PortId == request.PortId && strings.EqualFold(stub.Method, request.Method) && stub.Endpoint == request.Endpoint
+}
+
+func (stub *HttpStub) String() string {
+	return fmt.Sprintf("%s:%s %s %s", stub.ServiceName, stub.PortId, strings.ToUpper(stub.Method), stub.Endpoint)
+}
+
+// Creates a new response every time since the response body can only be read once
+func (stub *HttpStub) createResponse(request *http.Request) *http.Response {
+	header := http.Header{}
+	for name, value := range stub.Headers {
+		header.Set(name, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", stub.StatusCode, http.StatusText(stub.StatusCode)),
+		StatusCode:    stub.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(stub.Body)),
+		ContentLength: int64(len(stub.Body)),
+		Request:       request,
+	}
+}
+
+// HttpRequest is a record of an HTTP request made to a service port
+type HttpRequest struct {
+	ServiceName service.ServiceName
+	PortId      string
+	Method      string
+	Endpoint    string
+	ContentType string
+	Body        string
+	Headers     map[string]string
+}
+
+// StubHttp registers a canned response to HTTP requests made to a service port
+//
+// If multiple stubs match a request, the one registered last is used
+func (network *KurtestosisServiceNetwork) StubHttp(stub *HttpStub) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.httpStubs = append(network.httpStubs, stub)
+}
+
+// HttpRequests returns all the HTTP requests made to the services, in the order they were made
+func (network *KurtestosisServiceNetwork) HttpRequests() []*HttpRequest {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return append([]*HttpRequest{}, network.httpRequests...)
+}
+
+func (network *KurtestosisServiceNetwork) httpRequestServiceUnlocked(serviceIdentifier string, portId string, method string, contentType string, endpoint string, body string, headers map[string]string) (*http.Response, error) {
+	registration, err := network.getRegistrationUnlocked(serviceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	port, found := registration.GetConfig().GetPrivatePorts()[portId]
+	if !found {
+		return nil, fmt.Errorf("service %s has no port %s", registration.GetName(), portId)
+	}
+
+	request := &HttpRequest{
+		ServiceName: registration.GetName(),
+		PortId:      portId,
+		Method:      method,
+		Endpoint:    endpoint,
+		ContentType: contentType,
+		Body:        body,
+		Headers:     headers,
+	}
+
+	// Every request is recorded, even the ones that don't match any stubs
+	network.httpRequests = append(network.httpRequests, request)
+
+	// Only the matching stubs need a real request, it gets attached to the response
+	for i := len(network.httpStubs) - 1; i >= 0; i-- {
+		stub := network.httpStubs[i]
+		if !stub.Matches(request) {
+			continue
+		}
+
+		url := fmt.Sprintf("http://%s:%d/%s", registration.GetPrivateIP(), port.GetNumber(), strings.TrimPrefix(endpoint, "/"))
+		httpRequest, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request %s %s: %w", method, url, err)
+		}
+
+		if contentType != "" {
+			httpRequest.Header.Set("Content-Type", contentType)
+		}
+
+		for name, value := range headers {
+			httpRequest.Header.Set(name, value)
+		}
+
+		return stub.createResponse(httpRequest), nil
+	}
+
+	requestStr := fmt.Sprintf("%s:%s %s %s", registration.GetName(), portId, strings.ToUpper(method), endpoint)
+	if len(network.httpStubs) == 0 {
+		return nil, fmt.Errorf("no HTTP stub matches request %s, no HTTP stubs have been registered", requestStr)
+	}
+
+	registeredStubs := make([]string, len(network.httpStubs))
+	for i, stub := range network.httpStubs {
+		registeredStubs[i] = "\t" + stub.String()
+	}
+
+	return nil, fmt.Errorf("no HTTP stub matches request %s, registered HTTP stubs:\n%s", requestStr, strings.Join(registeredStubs, "\n"))
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/lib/shared_utils"
 	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/database_accessors/enclave_db"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_packages"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_packages/git_package_content_provider"
 )
 
@@ -13,7 +17,21 @@ const (
 	tempDirMode os.FileMode = 0700
 )
 
-func CreateLocalGitPackageContentProvider(artifactsPath string, enclaveDB *enclave_db.EnclaveDB) (*git_package_content_provider.GitPackageContentProvider, error) {
+// The repositories directory is shared by all the tests, even the ones running in parallel,
+// so any operations that might clone a repository into it need to be serialized
+var repositoriesLock sync.Mutex
+
+// LocalGitPackageContentProvider wraps a git package content provider
+// to make it safe to share its repositories directory between concurrently running tests
+//
+// In offline mode, only the repositories that have already been cloned into the repositories directory can be used
+type LocalGitPackageContentProvider struct {
+	*git_package_content_provider.GitPackageContentProvider
+	RepositoriesDirPath string
+	Offline             bool
+}
+
+func CreateLocalGitPackageContentProvider(artifactsPath string, enclaveDB *enclave_db.EnclaveDB, offline bool) (*LocalGitPackageContentProvider, error) {
 	var err error
 
 	// First we resolve the temporary filesystem paths
@@ -43,7 +61,83 @@ func CreateLocalGitPackageContentProvider(artifactsPath string, enclaveDB *encla
 	// so private github kurtosis packages will not work
 	githubPackageAuthProvider := git_package_content_provider.NewGitHubPackageAuthProvider(githubAuthDirPath)
 
-	return git_package_content_provider.NewGitPackageContentProvider(repositoriesDirPath, tempDirectoriesDirPath, githubPackageAuthProvider, enclaveDB), nil
+	return &LocalGitPackageContentProvider{
+		GitPackageContentProvider: git_package_content_provider.NewGitPackageContentProvider(repositoriesDirPath, tempDirectoriesDirPath, githubPackageAuthProvider, enclaveDB),
+		RepositoriesDirPath:       repositoriesDirPath,
+		Offline:                   offline,
+	}, nil
+}
+
+func (provider *LocalGitPackageContentProvider) GetOnDiskAbsolutePackageFilePath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, *startosis_errors.InterpretationError) {
+	repositoriesLock.Lock()
+	defer repositoriesLock.Unlock()
+
+	if interpretationErr := provider.checkOffline(absoluteModuleLocator); interpretationErr != nil {
+		return "", interpretationErr
+	}
+
+	return provider.GitPackageContentProvider.GetOnDiskAbsolutePackageFilePath(absoluteModuleLocator)
+}
+
+func (provider *LocalGitPackageContentProvider) GetOnDiskAbsolutePath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, *startosis_errors.InterpretationError) {
+	repositoriesLock.Lock()
+	defer repositoriesLock.Unlock()
+
+	if interpretationErr := provider.checkOffline(absoluteModuleLocator); interpretationErr != nil {
+		return "", interpretationErr
+	}
+
+	return provider.GitPackageContentProvider.GetOnDiskAbsolutePath(absoluteModuleLocator)
+}
+
+func (provider *LocalGitPackageContentProvider) GetModuleContents(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, *startosis_errors.InterpretationError) {
+	repositoriesLock.Lock()
+	defer repositoriesLock.Unlock()
+
+	if interpretationErr := provider.checkOffline(absoluteModuleLocator); interpretationErr != nil {
+		return "", interpretationErr
+	}
+
+	return provider.GitPackageContentProvider.GetModuleContents(absoluteModuleLocator)
+}
+
+func (provider *LocalGitPackageContentProvider) ClonePackage(packageId string) (string, *startosis_errors.InterpretationError) {
+	repositoriesLock.Lock()
+	defer repositoriesLock.Unlock()
+
+	if provider.Offline {
+		return "", startosis_errors.NewInterpretationError("Cannot clone package %s in offline mode", packageId)
+	}
+
+	return provider.GitPackageContentProvider.ClonePackage(packageId)
+}
+
+func (provider *LocalGitPackageContentProvider) CloneReplacedPackagesIfNeeded(currentPackageReplaceOptions map[string]string) *startosis_errors.InterpretationError {
+	repositoriesLock.Lock()
+	defer repositoriesLock.Unlock()
+
+	return provider.GitPackageContentProvider.CloneReplacedPackagesIfNeeded(currentPackageReplaceOptions)
+}
+
+// Makes sure that, in offline mode, the repository of a locator has already been cloned
+// since the git package content provider would otherwise try to clone it
+func (provider *LocalGitPackageContentProvider) checkOffline(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) *startosis_errors.InterpretationError {
+	if !provider.Offline {
+		return nil
+	}
+
+	// Locators that cannot be parsed are left for the git package content provider to report
+	parsedURL, err := shared_utils.ParseGitURL(absoluteModuleLocator.GetGitURL())
+	if err != nil {
+		return nil
+	}
+
+	repositoryPath := filepath.Join(provider.RepositoriesDirPath, parsedURL.GetRelativeRepoPath())
+	if _, err := os.Stat(repositoryPath); err != nil {
+		return startosis_errors.NewInterpretationError("Cannot load %s in offline mode, repository %s has not been fetched to %s", absoluteModuleLocator.GetLocator(), parsedURL.GetGitURL(), repositoryPath)
+	}
+
+	return nil
 }
 
 func createTempDirectory(dirPath string) error {
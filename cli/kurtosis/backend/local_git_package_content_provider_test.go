@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_packages"
+)
+
+func TestLocalGitPackageContentProviderOffline(t *testing.T) {
+	artifactsPath := t.TempDir()
+
+	enclaveDB, teardownEnclaveDB, err := CreateEnclaveDB()
+	if err != nil {
+		t.Fatalf("failed to create enclave database: %v", err)
+	}
+	defer teardownEnclaveDB()
+
+	provider, err := CreateLocalGitPackageContentProvider(artifactsPath, enclaveDB, true)
+	if err != nil {
+		t.Fatalf("failed to create package content provider: %v", err)
+	}
+
+	// A repository that has been fetched before is cloned in the repositories directory
+	cachedModulePath := filepath.Join(provider.RepositoriesDirPath, "kurtestosis-fixtures", "cached", "main.star")
+	err = os.MkdirAll(filepath.Dir(cachedModulePath), 0755)
+	if err != nil {
+		t.Fatalf("failed to create cached repository: %v", err)
+	}
+
+	err = os.WriteFile(cachedModulePath, []byte("greeting = \"hello\"\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write cached module: %v", err)
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		contents, interpretationErr := provider.GetModuleContents(startosis_packages.NewPackageAbsoluteLocator("github.com/kurtestosis-fixtures/cached/main.star", ""))
+		if interpretationErr != nil {
+			t.Fatalf("expected a cached module to load offline, got %v", interpretationErr)
+		}
+
+		if contents != "greeting = \"hello\"\n" {
+			t.Errorf("expected the contents of the cached module, got %q", contents)
+		}
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		_, interpretationErr := provider.GetModuleContents(startosis_packages.NewPackageAbsoluteLocator("github.com/kurtestosis-fixtures/uncached/main.star", ""))
+		if interpretationErr == nil {
+			t.Fatalf("expected a module that has not been fetched to fail offline")
+		}
+
+		expectedMessage := "Cannot load github.com/kurtestosis-fixtures/uncached/main.star in offline mode, repository https://github.com/kurtestosis-fixtures/uncached.git has not been fetched to " + filepath.Join(provider.RepositoriesDirPath, "kurtestosis-fixtures", "uncached")
+		if !strings.Contains(interpretationErr.Error(), expectedMessage) {
+			t.Errorf("expected an error containing %q, got %q", expectedMessage, interpretationErr.Error())
+		}
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		_, interpretationErr := provider.ClonePackage("github.com/kurtestosis-fixtures/uncached")
+		if interpretationErr == nil || !strings.Contains(interpretationErr.Error(), "Cannot clone package github.com/kurtestosis-fixtures/uncached in offline mode") {
+			t.Errorf("expected cloning to fail offline, got %v", interpretationErr)
+		}
+	})
+}
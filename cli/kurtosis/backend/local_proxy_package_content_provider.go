@@ -2,8 +2,10 @@ package backend
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/kurtosis-tech/kurtosis/api/golang/core/lib/shared_utils"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_packages"
 	"github.com/sirupsen/logrus"
@@ -13,30 +15,29 @@ import (
 
 // LocalProxyPackageContentProvider wraps an existing package content provider
 // to resolve local packages without accessing github
+//
+// Remote packages that have been replaced with local directories or vendored into the project are resolved from disk too.
+// If coverage is being collected, the local modules are instrumented when loaded
 type LocalProxyPackageContentProvider struct {
 	startosis_packages.PackageContentProvider
-	Project *core.KurtestosisProject
+	Project  *core.KurtestosisProject
+	Coverage *core.Coverage
 }
 
-func CreateLocalProxyPackageContentProvider(project *core.KurtestosisProject, packageContentProvider startosis_packages.PackageContentProvider) *LocalProxyPackageContentProvider {
+func CreateLocalProxyPackageContentProvider(project *core.KurtestosisProject, packageContentProvider startosis_packages.PackageContentProvider, coverage *core.Coverage) *LocalProxyPackageContentProvider {
 	return &LocalProxyPackageContentProvider{
 		PackageContentProvider: packageContentProvider,
 		Project:                project,
+		Coverage:               coverage,
 	}
 }
 
 func (provider *LocalProxyPackageContentProvider) GetModuleContents(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, *startosis_errors.InterpretationError) {
 	// This provider will check whether the git URL matches our local project and if so,
 	// will substitute remote git queries for local ones
-	gitUrl := absoluteModuleLocator.GetGitURL()
-	packageName := provider.Project.KurotosisYml.PackageName
-	packageRoot := provider.Project.Path
-
-	// Let's see if the requested module comes from the local package
-	if strings.HasPrefix(gitUrl, packageName) {
-		// If so, we'll replace the git URL with a local path
-		localName := strings.Replace(gitUrl, packageName, packageRoot, 1)
-		logrus.Debugf("Loading module content for %s from %s", gitUrl, localName)
+	localName, isLocal := provider.getLocalPath(absoluteModuleLocator)
+	if isLocal {
+		logrus.Debugf("Loading module content for %s from %s", absoluteModuleLocator.GetGitURL(), localName)
 
 		// And load the contents from disk
 		content, contentErr := os.ReadFile(localName)
@@ -46,9 +47,155 @@ func (provider *LocalProxyPackageContentProvider) GetModuleContents(absoluteModu
 			return "", startosis_errors.NewInterpretationError("Failed to load module content from %s: %v", localName, contentErr)
 		}
 
+		return provider.instrument(localName, string(content))
+	}
+
+	// Dependencies available on disk are loaded from there as well, but they are not part of the coverage
+	dependencyName, isOnDisk, dependencyErr := provider.getDependencyPath(absoluteModuleLocator)
+	if dependencyErr != nil {
+		return "", dependencyErr
+	}
+	if isOnDisk {
+		logrus.Debugf("Loading module content for %s from %s", absoluteModuleLocator.GetGitURL(), dependencyName)
+
+		content, contentErr := os.ReadFile(dependencyName)
+		if contentErr != nil {
+			return "", startosis_errors.NewInterpretationError("Failed to load module content from %s: %v", dependencyName, contentErr)
+		}
+
 		return string(content), nil
 	}
 
 	// Any non-local queries are proxied to the wrapped PackageContentProvider
 	return provider.PackageContentProvider.GetModuleContents(absoluteModuleLocator)
 }
+
+// GetOnDiskAbsolutePath resolves paths of local files and directories, e.g. the ones passed to plan.upload_files
+func (provider *LocalProxyPackageContentProvider) GetOnDiskAbsolutePath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, *startosis_errors.InterpretationError) {
+	localName, isLocal := provider.getLocalPath(absoluteModuleLocator)
+	if !isLocal {
+		var dependencyErr *startosis_errors.InterpretationError
+		localName, isLocal, dependencyErr = provider.getDependencyPath(absoluteModuleLocator)
+		if dependencyErr != nil {
+			return "", dependencyErr
+		}
+	}
+
+	if isLocal {
+		if _, statErr := os.Stat(localName); statErr != nil {
+			return "", startosis_errors.NewInterpretationError("Failed to find %s: %v", localName, statErr)
+		}
+
+		return localName, nil
+	}
+
+	return provider.PackageContentProvider.GetOnDiskAbsolutePath(absoluteModuleLocator)
+}
+
+// GetOnDiskAbsolutePackageFilePath resolves paths of local files, e.g. the ones used as templates
+func (provider *LocalProxyPackageContentProvider) GetOnDiskAbsolutePackageFilePath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, *startosis_errors.InterpretationError) {
+	localName, isLocal := provider.getLocalPath(absoluteModuleLocator)
+	if !isLocal {
+		var dependencyErr *startosis_errors.InterpretationError
+		localName, isLocal, dependencyErr = provider.getDependencyPath(absoluteModuleLocator)
+		if dependencyErr != nil {
+			return "", dependencyErr
+		}
+	}
+
+	if isLocal {
+		return localName, nil
+	}
+
+	return provider.PackageContentProvider.GetOnDiskAbsolutePackageFilePath(absoluteModuleLocator)
+}
+
+// Replaces the git URL with a local path if the requested file comes from the local package
+func (provider *LocalProxyPackageContentProvider) getLocalPath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, bool) {
+	gitUrl := absoluteModuleLocator.GetGitURL()
+	packageName := provider.Project.KurotosisYml.PackageName
+	packageRoot := provider.Project.Path
+
+	if !strings.HasPrefix(gitUrl, packageName) {
+		return "", false
+	}
+
+	return strings.Replace(gitUrl, packageName, packageRoot, 1), true
+}
+
+// Replaces the git URL with a local path if the requested file comes from a package that has been replaced
+// with a local directory or vendored into the project, in that order
+func (provider *LocalProxyPackageContentProvider) getDependencyPath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, bool, *startosis_errors.InterpretationError) {
+	localReplacePath, isLocalReplace := provider.Project.LocalReplacePath(absoluteModuleLocator.GetLocator())
+	if isLocalReplace {
+		return localReplacePath, true, nil
+	}
+
+	return provider.getVendoredPath(absoluteModuleLocator)
+}
+
+// Replaces the git URL with a path in the vendor directory if the requested file comes from a vendored package
+//
+// Vendored packages can only be loaded at the version they were vendored at
+func (provider *LocalProxyPackageContentProvider) getVendoredPath(absoluteModuleLocator *startosis_packages.PackageAbsoluteLocator) (string, bool, *startosis_errors.InterpretationError) {
+	parsedURL, err := shared_utils.ParseGitURL(absoluteModuleLocator.GetGitURL())
+	if err != nil {
+		return "", false, nil
+	}
+
+	// The whole package is vendored, so if its directory is there, the file is too
+	vendorDirPath := provider.Project.VendorDirPath()
+	if _, statErr := os.Stat(filepath.Join(vendorDirPath, parsedURL.GetRelativeRepoPath())); statErr != nil {
+		return "", false, nil
+	}
+
+	packageName := shared_utils.GithubDomainPrefix + "/" + parsedURL.GetRelativeRepoPath()
+	lockedPackage := provider.Project.Lockfile.FindPackage(packageName)
+	if lockedPackage == nil {
+		return "", false, startosis_errors.NewInterpretationError("Package %s is vendored but not pinned in %s", packageName, core.LockfileName)
+	}
+
+	version := absoluteModuleLocator.GetTagBranchOrCommit()
+	if version == "" {
+		version = parsedURL.GetTagBranchOrCommit()
+	}
+
+	if !lockedPackage.Matches(version) {
+		return "", false, startosis_errors.NewInterpretationError("Cannot load %s at version %q, package %s is vendored at version %q (commit %s). Run kurtestosis vendor to update the vendor directory", absoluteModuleLocator.GetLocator(), version, packageName, lockedPackage.Version, lockedPackage.Commit)
+	}
+
+	// Locators of the package itself, e.g. the ones passed to plan.upload_files, don't have a file path
+	if parsedURL.GetRelativeFilePath() == "" {
+		return filepath.Join(vendorDirPath, parsedURL.GetRelativeRepoPath()), true, nil
+	}
+
+	return filepath.Join(vendorDirPath, parsedURL.GetRelativeFilePath()), true, nil
+}
+
+// Adds coverage instrumentation to a local module if coverage is being collected
+func (provider *LocalProxyPackageContentProvider) instrument(localName string, content string) (string, *startosis_errors.InterpretationError) {
+	// Only starlark modules can be instrumented, other files can be loaded using read_file
+	if provider.Coverage == nil || filepath.Ext(localName) != ".star" {
+		return content, nil
+	}
+
+	relativeName, relativeNameErr := filepath.Rel(provider.Project.Path, localName)
+	if relativeNameErr != nil {
+		return "", startosis_errors.NewInterpretationError("Failed to determine path of %s relative to project root %s: %v", localName, provider.Project.Path, relativeNameErr)
+	}
+
+	if !provider.Coverage.Includes(relativeName) {
+		return content, nil
+	}
+
+	instrumentedContent, instrumentedLines, instrumentErr := core.InstrumentModule(relativeName, content)
+	if instrumentErr != nil {
+		logrus.Errorf("Failed to instrument module %s for coverage: %v", localName, instrumentErr)
+
+		return "", startosis_errors.NewInterpretationError("Failed to instrument module %s for coverage: %v", localName, instrumentErr)
+	}
+
+	provider.Coverage.Register(relativeName, instrumentedLines)
+
+	return instrumentedContent, nil
+}
@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/builtins/import_module"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_packages"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// ModuleReplacements replaces modules imported using import_module with test doubles
+//
+// The interpreter keeps its module cache to itself, so instead of populating the cache
+// the import_module builtin is wrapped to return the replacement modules. Modules that have been imported
+// before being replaced get their members replaced in place so that everyone holding a reference to them sees the change
+type ModuleReplacements struct {
+	packageId              string
+	packageContentProvider startosis_packages.PackageContentProvider
+	packageReplaceOptions  map[string]string
+
+	// Replacement modules by their absolute locators
+	replacements map[string]*starlarkstruct.Module
+	// Modules imported so far by their absolute locators
+	imported map[string]*starlarkstruct.Module
+}
+
+func NewModuleReplacements(packageId string, packageContentProvider startosis_packages.PackageContentProvider, packageReplaceOptions map[string]string) *ModuleReplacements {
+	return &ModuleReplacements{
+		packageId:              packageId,
+		packageContentProvider: packageContentProvider,
+		packageReplaceOptions:  packageReplaceOptions,
+		replacements:           map[string]*starlarkstruct.Module{},
+		imported:               map[string]*starlarkstruct.Module{},
+	}
+}
+
+// Replace makes the module under locator resolve to a module with the given members
+//
+// The locator is resolved the same way import_module resolves it when called from the module under callerLocator
+func (moduleReplacements *ModuleReplacements) Replace(callerLocator string, locator string, members starlark.StringDict) error {
+	absoluteLocator, err := moduleReplacements.getAbsoluteLocator(callerLocator, locator)
+	if err != nil {
+		return err
+	}
+
+	if module, ok := moduleReplacements.imported[absoluteLocator]; ok {
+		module.Members = members
+		moduleReplacements.replacements[absoluteLocator] = module
+
+		return nil
+	}
+
+	moduleReplacements.replacements[absoluteLocator] = &starlarkstruct.Module{
+		Name:    absoluteLocator,
+		Members: members,
+	}
+
+	return nil
+}
+
+// WrapImportModule returns an import_module builtin that resolves the replaced modules to their replacements
+func (moduleReplacements *ModuleReplacements) WrapImportModule(importModule *starlark.Builtin) *starlark.Builtin {
+	return starlark.NewBuiltin(importModule.Name(), func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		// Any errors resolving the locator are left for the original builtin to report
+		absoluteLocator, err := moduleReplacements.getAbsoluteLocator(thread.CallStack().At(1).Pos.Filename(), getModuleFileArgument(args, kwargs))
+		if err == nil {
+			if module, ok := moduleReplacements.replacements[absoluteLocator]; ok {
+				return module, nil
+			}
+		}
+
+		// The original builtin is called directly, without adding a frame to the call stack,
+		// since kurtosis uses the call stack to resolve relative locators
+		value, err := importModule.CallInternal(thread, args, kwargs)
+		if err != nil {
+			return nil, err
+		}
+
+		if module, ok := value.(*starlarkstruct.Module); ok && absoluteLocator != "" {
+			moduleReplacements.imported[absoluteLocator] = module
+		}
+
+		return value, nil
+	})
+}
+
+func (moduleReplacements *ModuleReplacements) getAbsoluteLocator(callerLocator string, locator string) (string, error) {
+	if locator == "" {
+		return "", fmt.Errorf("module locator cannot be empty")
+	}
+
+	absoluteLocator, interpretationErr := moduleReplacements.packageContentProvider.GetAbsoluteLocator(moduleReplacements.packageId, callerLocator, locator, moduleReplacements.packageReplaceOptions)
+	if interpretationErr != nil {
+		return "", interpretationErr
+	}
+
+	return absoluteLocator.GetLocator(), nil
+}
+
+// Returns the module_file argument of an import_module call, or an empty string if it's missing or not a string
+func getModuleFileArgument(args starlark.Tuple, kwargs []starlark.Tuple) string {
+	var moduleFile starlark.Value
+	if len(args) > 0 {
+		moduleFile = args[0]
+	}
+
+	for _, kwarg := range kwargs {
+		if kwarg[0] == starlark.String(import_module.ModuleFileArgName) {
+			moduleFile = kwarg[1]
+		}
+	}
+
+	moduleFileString, _ := moduleFile.(starlark.String)
+
+	return moduleFileString.GoString()
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"sync"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine"
+	"go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// startosis_engine.Predeclared disables time.now() by writing to the time module shared by the whole process,
+// and it gets called every time a module is interpreted. To run interpreters concurrently, the predeclared builtins
+// are built one at a time and every interpreter gets its own copy of the time module so that the shared one
+// is never read while another interpreter writes to it
+var predeclaredMutex sync.Mutex
+
+// Predeclared returns the kurtosis predeclared builtins with a copy of the shared time module
+func Predeclared() starlark.StringDict {
+	predeclaredMutex.Lock()
+	defer predeclaredMutex.Unlock()
+
+	return isolateTimeModule(startosis_engine.Predeclared())
+}
+
+// PredeclaredLock lets a single interpreter build the predeclared builtins of its modules
+//
+// The interpreter does not let us wrap the building of the predeclared builtins, so the lock is taken
+// by KurtestosisServiceNetwork.GetEnclaveUuid, which the interpreter calls right before it calls startosis_engine.Predeclared,
+// and released by the builtins processor the interpreter calls right after.
+// The interpreter can fail in between, so whoever runs the interpretation needs to defer Unlock.
+//
+// A lock is only ever used by the goroutine running the interpretation
+type PredeclaredLock struct {
+	locked bool
+}
+
+func NewPredeclaredLock() *PredeclaredLock {
+	return &PredeclaredLock{}
+}
+
+// Release replaces the shared time module in the predeclared builtins built by the interpreter with a copy
+// and lets other interpreters build theirs
+func (lock *PredeclaredLock) Release(predeclared starlark.StringDict) starlark.StringDict {
+	defer lock.Unlock()
+
+	return isolateTimeModule(predeclared)
+}
+
+// Unlock lets other interpreters build their predeclared builtins if the interpreter still holds the lock
+func (lock *PredeclaredLock) Unlock() {
+	if !lock.locked {
+		return
+	}
+
+	lock.locked = false
+	predeclaredMutex.Unlock()
+}
+
+func (lock *PredeclaredLock) lock() {
+	predeclaredMutex.Lock()
+	lock.locked = true
+}
+
+func isolateTimeModule(predeclared starlark.StringDict) starlark.StringDict {
+	timeModule, ok := predeclared[time.Module.Name].(*starlarkstruct.Module)
+	if !ok {
+		return predeclared
+	}
+
+	members := make(starlark.StringDict, len(timeModule.Members))
+	for name, member := range timeModule.Members {
+		members[name] = member
+	}
+
+	predeclared[time.Module.Name] = &starlarkstruct.Module{
+		Name:    timeModule.Name,
+		Members: members,
+	}
+
+	return predeclared
+}
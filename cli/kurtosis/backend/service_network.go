@@ -2,35 +2,75 @@ package backend
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
+	"sort"
+	"sync"
 
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/container"
 	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/enclave"
 	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/exec_result"
 	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/uuid_generator"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/service_network"
-	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/service_network/render_templates"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/service_network/service_identifiers"
 	"github.com/kurtosis-tech/kurtosis/core/server/commons/enclave_data_directory"
 )
 
 const (
 	enclaveUUID = "kurtestosis-enclave"
+
+	// Services get private IPs from 10.0.0.0/16 starting at 10.0.0.2
+	serviceIPOffset = 2
 )
 
 var (
 	apiContainerInfo = service_network.NewApiContainerInfo(net.IPv4(0, 0, 0, 0), 0, "0.0.0")
 
+	serviceIPPrefix = []byte{10, 0}
+
 	// Make sure KurtestosisServiceNetwork implements service_network.ServiceNetwork
 	_ service_network.ServiceNetwork = (*KurtestosisServiceNetwork)(nil) 
 )
 
-type KurtestosisServiceNetwork struct {}
+// KurtestosisServiceNetwork is an in-memory fake of a kurtosis service network
+//
+// Services are only recorded, no containers are started. Every service gets a deterministic UUID
+// and a private IP address based on the order in which it was added
+type KurtestosisServiceNetwork struct {
+	mutex sync.Mutex
+
+	// Taken whenever the interpreter builds the predeclared builtins of a module
+	predeclaredLock *PredeclaredLock
+
+	// Registrations of the existing services, keyed by service name
+	registrations map[service.ServiceName]*service.ServiceRegistration
+	// Names of the existing services in the order they were added
+	serviceNames []service.ServiceName
+	// Identifiers of all the services ever added, including the removed ones
+	historicalServiceIdentifiers service_identifiers.ServiceIdentifiers
+
+	// Canned results of commands executed in the services, in the order they were registered
+	execStubs []*ExecStub
+	// Canned responses to HTTP requests made to the services, in the order they were registered
+	httpStubs []*HttpStub
+	// HTTP requests made to the services, in the order they were made
+	httpRequests []*HttpRequest
+
+	// Files artifacts keyed by their names
+	filesArtifacts map[string]*FilesArtifact
+	// Number of generated files artifact names
+	filesArtifactNameCounter int
+}
 
-func CreateKurtestosisServiceNetwork() *KurtestosisServiceNetwork {
-	return &KurtestosisServiceNetwork{}
+func CreateKurtestosisServiceNetwork(predeclaredLock *PredeclaredLock) *KurtestosisServiceNetwork {
+	return &KurtestosisServiceNetwork{
+		predeclaredLock: predeclaredLock,
+		registrations:  map[service.ServiceName]*service.ServiceRegistration{},
+		filesArtifacts: map[string]*FilesArtifact{},
+	}
 }
 
 func (network *KurtestosisServiceNetwork) AddService(
@@ -41,7 +81,10 @@ func (network *KurtestosisServiceNetwork) AddService(
 	*service.Service,
 	error,
 ) {
-	return nil, unimplemented("AddService")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.addServiceUnlocked(serviceName, serviceConfig)
 }
 
 func (network *KurtestosisServiceNetwork) AddServices(
@@ -53,7 +96,28 @@ func (network *KurtestosisServiceNetwork) AddServices(
 	map[service.ServiceName]error,
 	error,
 ) {
-	return nil, nil, unimplemented("AddServices")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	// We add the services in a stable order so that they get the same UUIDs and IPs on every run
+	serviceNames := []service.ServiceName{}
+	for serviceName := range serviceConfigs {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Slice(serviceNames, func(i, j int) bool { return serviceNames[i] < serviceNames[j] })
+
+	startedServices := map[service.ServiceName]*service.Service{}
+	failedServices := map[service.ServiceName]error{}
+	for _, serviceName := range serviceNames {
+		startedService, err := network.addServiceUnlocked(serviceName, serviceConfigs[serviceName])
+		if err != nil {
+			failedServices[serviceName] = err
+		} else {
+			startedServices[serviceName] = startedService
+		}
+	}
+
+	return startedServices, failedServices, nil
 }
 
 func (network *KurtestosisServiceNetwork) UpdateService(
@@ -64,57 +128,108 @@ func (network *KurtestosisServiceNetwork) UpdateService(
 	*service.Service,
 	error,
 ) {
-	return nil, unimplemented("AddServices")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.updateServiceUnlocked(serviceName, updateServiceConfig)
 }
 
 func (network *KurtestosisServiceNetwork) UpdateServices(
-    ctx context.Context,
-    updateServiceConfigs map[service.ServiceName]*service.ServiceConfig,
-    batchSize int,
+	ctx context.Context,
+	updateServiceConfigs map[service.ServiceName]*service.ServiceConfig,
+	batchSize int,
 ) (
-    map[service.ServiceName]*service.Service,
-    map[service.ServiceName]error,
-    error,
+	map[service.ServiceName]*service.Service,
+	map[service.ServiceName]error,
+	error,
 ) {
-    return nil, nil, unimplemented("UpdateServices")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	updatedServices := map[service.ServiceName]*service.Service{}
+	failedServices := map[service.ServiceName]error{}
+	for serviceName, updateServiceConfig := range updateServiceConfigs {
+		updatedService, err := network.updateServiceUnlocked(serviceName, updateServiceConfig)
+		if err != nil {
+			failedServices[serviceName] = err
+		} else {
+			updatedServices[serviceName] = updatedService
+		}
+	}
+
+	return updatedServices, failedServices, nil
 }
 
 func (network *KurtestosisServiceNetwork) RemoveService(ctx context.Context, serviceIdentifier string) (service.ServiceUUID, error) {
-    return "", unimplemented("RemoveService")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	registration, err := network.getRegistrationUnlocked(serviceIdentifier)
+	if err != nil {
+		return "", err
+	}
+
+	delete(network.registrations, registration.GetName())
+	for i, serviceName := range network.serviceNames {
+		if serviceName == registration.GetName() {
+			network.serviceNames = append(network.serviceNames[:i], network.serviceNames[i+1:]...)
+
+			break
+		}
+	}
+
+	return registration.GetUUID(), nil
 }
 
 func (network *KurtestosisServiceNetwork) StartService(ctx context.Context, serviceIdentifier string) error {
-    return unimplemented("StartService")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.setServiceStatusUnlocked(serviceIdentifier, service.ServiceStatus_Started)
 }
 
 func (network *KurtestosisServiceNetwork) StartServices(
-    ctx context.Context,
-    serviceIdentifiers []string,
+	ctx context.Context,
+	serviceIdentifiers []string,
 ) (
-    map[service.ServiceUUID]bool,
-    map[service.ServiceUUID]error,
-    error,
+	map[service.ServiceUUID]bool,
+	map[service.ServiceUUID]error,
+	error,
 ) {
-    return nil, nil, unimplemented("StartServices")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.setServicesStatusUnlocked(serviceIdentifiers, service.ServiceStatus_Started)
 }
 
 func (network *KurtestosisServiceNetwork) StopService(ctx context.Context, serviceIdentifier string) error {
-    return unimplemented("StopService")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.setServiceStatusUnlocked(serviceIdentifier, service.ServiceStatus_Stopped)
 }
 
 func (network *KurtestosisServiceNetwork) StopServices(
-    ctx context.Context,
-    serviceIdentifiers []string,
+	ctx context.Context,
+	serviceIdentifiers []string,
 ) (
-    map[service.ServiceUUID]bool,
-    map[service.ServiceUUID]error,
-    error,
+	map[service.ServiceUUID]bool,
+	map[service.ServiceUUID]error,
+	error,
 ) {
-    return nil, nil, unimplemented("StopServices")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.setServicesStatusUnlocked(serviceIdentifiers, service.ServiceStatus_Stopped)
 }
 
 func (network *KurtestosisServiceNetwork) RunExec(ctx context.Context, serviceIdentifier string, userServiceCommand []string) (*exec_result.ExecResult, error) {
-    return nil, unimplemented("RunExec")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	_, execResult, err := network.runExecUnlocked(serviceIdentifier, userServiceCommand)
+
+	return execResult, err
 }
 
 func (network *KurtestosisServiceNetwork) RunExecs(
@@ -125,19 +240,56 @@ func (network *KurtestosisServiceNetwork) RunExecs(
     map[service.ServiceUUID]error,
     error,
 ) {
-    return nil, nil, unimplemented("RunExecs")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	execResults := map[service.ServiceUUID]*exec_result.ExecResult{}
+	failedExecs := map[service.ServiceUUID]error{}
+	for serviceIdentifier, userServiceCommand := range userServiceCommands {
+		registration, execResult, err := network.runExecUnlocked(serviceIdentifier, userServiceCommand)
+		if registration == nil {
+			return nil, nil, err
+		}
+
+		if err != nil {
+			failedExecs[registration.GetUUID()] = err
+		} else {
+			execResults[registration.GetUUID()] = execResult
+		}
+	}
+
+	return execResults, failedExecs, nil
 }
 
 func (network *KurtestosisServiceNetwork) HttpRequestService(ctx context.Context, serviceIdentifier string, portId string, method string, contentType string, endpoint string, body string, headers map[string]string) (*http.Response, error) {
-    return nil, unimplemented("HttpRequestService")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.httpRequestServiceUnlocked(serviceIdentifier, portId, method, contentType, endpoint, body, headers)
 }
 
 func (network *KurtestosisServiceNetwork) GetService(ctx context.Context, serviceIdentifier string) (*service.Service, error) {
-    return nil, unimplemented("GetService")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	registration, err := network.getRegistrationUnlocked(serviceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return createService(registration), nil
 }
 
 func (network *KurtestosisServiceNetwork) GetServices(ctx context.Context) (map[service.ServiceUUID]*service.Service, error) {
-    return nil, unimplemented("GetServices")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	services := map[service.ServiceUUID]*service.Service{}
+	for _, registration := range network.registrations {
+		services[registration.GetUUID()] = createService(registration)
+	}
+
+	return services, nil
 }
 
 func (network *KurtestosisServiceNetwork) CopyFilesFromService(ctx context.Context, serviceIdentifier string, srcPath string, artifactName string) (enclave_data_directory.FilesArtifactUUID, error) {
@@ -145,43 +297,146 @@ func (network *KurtestosisServiceNetwork) CopyFilesFromService(ctx context.Conte
 }
 
 func (network *KurtestosisServiceNetwork) GetServiceNames() (map[service.ServiceName]bool, error) {
-    return nil, unimplemented("GetServiceNames")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	serviceNames := map[service.ServiceName]bool{}
+	for serviceName := range network.registrations {
+		serviceNames[serviceName] = true
+	}
+
+	return serviceNames, nil
 }
 
 func (network *KurtestosisServiceNetwork) GetExistingAndHistoricalServiceIdentifiers() (service_identifiers.ServiceIdentifiers, error) {
-    return nil, unimplemented("GetExistingAndHistoricalServiceIdentifiers")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return append(service_identifiers.ServiceIdentifiers{}, network.historicalServiceIdentifiers...), nil
 }
 
 func (network *KurtestosisServiceNetwork) ExistServiceRegistration(serviceName service.ServiceName) (bool, error) {
-    return false, unimplemented("ExistServiceRegistration")
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	_, exists := network.registrations[serviceName]
+
+	return exists, nil
 }
 
-func (network *KurtestosisServiceNetwork) RenderTemplates(templatesAndDataByDestinationRelFilepath map[string]*render_templates.TemplateData, artifactName string) (enclave_data_directory.FilesArtifactUUID, error) {
-    return "", unimplemented("RenderTemplates")
+func (network *KurtestosisServiceNetwork) GetApiContainerInfo() *service_network.ApiContainerInfo {
+    return apiContainerInfo
 }
 
-func (network *KurtestosisServiceNetwork) UploadFilesArtifact(data io.Reader, contentMd5 []byte, artifactName string) (enclave_data_directory.FilesArtifactUUID, error) {
-    return "", unimplemented("UploadFilesArtifact")
+// GetEnclaveUuid is only called by the interpreter right before it builds the predeclared builtins of a module
+// so it also takes the predeclared lock
+func (network *KurtestosisServiceNetwork) GetEnclaveUuid() enclave.EnclaveUUID {
+    network.predeclaredLock.lock()
+
+    return enclave.EnclaveUUID(enclaveUUID)
 }
 
-func (network *KurtestosisServiceNetwork) GetFilesArtifactMd5(artifactName string) (enclave_data_directory.FilesArtifactUUID, []byte, bool, error) {
-    return "", nil, false, unimplemented("GetFilesArtifactMd5")
+func (network *KurtestosisServiceNetwork) addServiceUnlocked(serviceName service.ServiceName, serviceConfig *service.ServiceConfig) (*service.Service, error) {
+	if _, exists := network.registrations[serviceName]; exists {
+		return nil, fmt.Errorf("service %s already exists", serviceName)
+	}
+
+	// Every service ever added gets its own UUID and IP, even if a service with the same name has been removed before
+	serviceIndex := len(network.historicalServiceIdentifiers)
+	serviceUUID := service.ServiceUUID(fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s/%d", serviceName, serviceIndex)))))
+	serviceIP := net.IPv4(serviceIPPrefix[0], serviceIPPrefix[1], byte((serviceIndex+serviceIPOffset)>>8), byte(serviceIndex+serviceIPOffset))
+
+	registration := service.NewServiceRegistration(serviceName, serviceUUID, enclave.EnclaveUUID(enclaveUUID), serviceIP, string(serviceName))
+	registration.SetConfig(serviceConfig)
+	registration.SetStatus(service.ServiceStatus_Started)
+
+	network.registrations[serviceName] = registration
+	network.serviceNames = append(network.serviceNames, serviceName)
+	network.historicalServiceIdentifiers = append(network.historicalServiceIdentifiers, service_identifiers.NewServiceIdentifier(serviceUUID, serviceName))
+
+	return createService(registration), nil
 }
 
-func (network *KurtestosisServiceNetwork) UpdateFilesArtifact(fileArtifactUuid enclave_data_directory.FilesArtifactUUID, updatedContent io.Reader, contentMd5 []byte) error {
-    return unimplemented("UpdateFilesArtifact")
+func (network *KurtestosisServiceNetwork) updateServiceUnlocked(serviceName service.ServiceName, updateServiceConfig *service.ServiceConfig) (*service.Service, error) {
+	registration, exists := network.registrations[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("service %s does not exist", serviceName)
+	}
+
+	registration.SetConfig(updateServiceConfig)
+
+	return createService(registration), nil
 }
 
-func (network *KurtestosisServiceNetwork) GetUniqueNameForFileArtifact() (string, error) {
-    return "", unimplemented("GetUniqueNameForFileArtifact")
+func (network *KurtestosisServiceNetwork) setServiceStatusUnlocked(serviceIdentifier string, status service.ServiceStatus) error {
+	registration, err := network.getRegistrationUnlocked(serviceIdentifier)
+	if err != nil {
+		return err
+	}
+
+	registration.SetStatus(status)
+
+	return nil
 }
 
-func (network *KurtestosisServiceNetwork) GetApiContainerInfo() *service_network.ApiContainerInfo {
-    return apiContainerInfo
+func (network *KurtestosisServiceNetwork) setServicesStatusUnlocked(serviceIdentifiers []string, status service.ServiceStatus) (map[service.ServiceUUID]bool, map[service.ServiceUUID]error, error) {
+	successfulServices := map[service.ServiceUUID]bool{}
+	for _, serviceIdentifier := range serviceIdentifiers {
+		registration, err := network.getRegistrationUnlocked(serviceIdentifier)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		registration.SetStatus(status)
+		successfulServices[registration.GetUUID()] = true
+	}
+
+	return successfulServices, map[service.ServiceUUID]error{}, nil
 }
 
-func (network *KurtestosisServiceNetwork) GetEnclaveUuid() enclave.EnclaveUUID {
-    return enclave.EnclaveUUID(enclaveUUID)
+// Finds the registration of an existing service by its name, UUID or shortened UUID
+func (network *KurtestosisServiceNetwork) getRegistrationUnlocked(serviceIdentifier string) (*service.ServiceRegistration, error) {
+	if registration, exists := network.registrations[service.ServiceName(serviceIdentifier)]; exists {
+		return registration, nil
+	}
+
+	matchingRegistrations := []*service.ServiceRegistration{}
+	for _, serviceName := range network.serviceNames {
+		registration := network.registrations[serviceName]
+		serviceUUID := string(registration.GetUUID())
+		if serviceUUID == serviceIdentifier || uuid_generator.ShortenedUUIDString(serviceUUID) == serviceIdentifier {
+			matchingRegistrations = append(matchingRegistrations, registration)
+		}
+	}
+
+	switch len(matchingRegistrations) {
+	case 0:
+		return nil, fmt.Errorf("service %s does not exist", serviceIdentifier)
+	case 1:
+		return matchingRegistrations[0], nil
+	default:
+		return nil, fmt.Errorf("found multiple services matching shortened UUID %s", serviceIdentifier)
+	}
+}
+
+// Creates a service object from a registration, as if its container was running
+func createService(registration *service.ServiceRegistration) *service.Service {
+	serviceConfig := registration.GetConfig()
+
+	containerStatus := container.ContainerStatus_Running
+	if registration.GetStatus() != service.ServiceStatus_Started {
+		containerStatus = container.ContainerStatus_Stopped
+	}
+
+	serviceContainer := container.NewContainer(
+		containerStatus,
+		serviceConfig.GetContainerImageName(),
+		serviceConfig.GetEntrypointArgs(),
+		serviceConfig.GetCmdArgs(),
+		serviceConfig.GetEnvVars(),
+	)
+
+	return service.NewService(registration, serviceConfig.GetPrivatePorts(), nil, serviceConfig.GetPublicPorts(), serviceContainer)
 }
 
 func unimplemented(methodName string) error {
@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/image_download_mode"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/service_network"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/enclave_structure"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/instructions_plan"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/instructions_plan/resolver"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/interpretation_time_value_store"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_instruction"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_instruction/plan_module"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_types"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/runtime_value_store"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_constants"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_packages"
+	"github.com/sirupsen/logrus"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// TestPlan collects the plan instructions added by a test
+//
+// The interpreter keeps its instructions plan to itself, so the test plan replaces the members of the plan module
+// passed to a test with ones that add the instructions to its own instructions plan. This way the instructions
+// can be inspected while the test is still running and, if requested, executed as soon as they are added.
+// Kurtosis on the other hand only executes the plan once the whole package has been interpreted
+type TestPlan struct {
+	ctx              context.Context
+	execute          bool
+	instructionsPlan *instructions_plan.InstructionsPlan
+	planModule       *starlarkstruct.Module
+
+	mutex        sync.Mutex
+	instructions []*TestPlanInstruction
+	// Number of instructions from the instructions plan that have already been executed
+	executed int
+}
+
+// Runtime values are referenced by random UUIDs, e.g. {{kurtosis:<uuid>:output.runtime_value}}
+var runtimeValueUuidRegexp = regexp.MustCompile(`\{\{kurtosis:([a-f0-9]{32}):`)
+
+// TestPlanInstruction is an instruction added to the plan by a test, along with the arguments it was called with
+type TestPlanInstruction struct {
+	Name   string
+	Args   starlark.Tuple
+	Kwargs []starlark.Tuple
+
+	Instruction kurtosis_instruction.KurtosisInstruction
+}
+
+// Argument returns the value of an argument the instruction was called with, passed either by position or by name
+//
+// Returns nil if the argument has not been passed
+func (instruction *TestPlanInstruction) Argument(index int, name string) starlark.Value {
+	if index < len(instruction.Args) {
+		return instruction.Args[index]
+	}
+
+	for _, kwarg := range instruction.Kwargs {
+		if kwarg[0] == starlark.String(name) {
+			return kwarg[1]
+		}
+	}
+
+	return nil
+}
+
+func NewTestPlan(
+	ctx context.Context,
+	execute bool,
+	packageId string,
+	serviceNetwork service_network.ServiceNetwork,
+	runtimeValueStore *runtime_value_store.RuntimeValueStore,
+	packageContentProvider startosis_packages.PackageContentProvider,
+	packageReplaceOptions map[string]string,
+	interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore,
+	starlarkValueSerde *kurtosis_types.StarlarkValueSerde,
+) *TestPlan {
+	instructionsPlan := instructions_plan.NewInstructionsPlan()
+	kurtosisPlanInstructions := startosis_engine.KurtosisPlanInstructions(
+		packageId,
+		serviceNetwork,
+		runtimeValueStore,
+		packageContentProvider,
+		packageReplaceOptions,
+		false, // nonBlockingMode
+		interpretationTimeValueStore,
+		image_download_mode.ImageDownloadMode_Missing,
+	)
+
+	return &TestPlan{
+		// Instructions that run things in parallel expect the parallelism to be part of the context
+		ctx:              context.WithValue(ctx, startosis_constants.ParallelismParam, 1),
+		execute:          execute,
+		instructionsPlan: instructionsPlan,
+		planModule: plan_module.PlanModule(
+			instructionsPlan,
+			enclave_structure.NewEnclaveComponents(),
+			starlarkValueSerde,
+			resolver.NewInstructionsPlanMask(0),
+			kurtosisPlanInstructions,
+		),
+	}
+}
+
+// Attach replaces the members of the plan module passed to a test with the ones of the test plan
+func (testPlan *TestPlan) Attach(plan starlark.Value) error {
+	planModule, ok := plan.(*starlarkstruct.Module)
+	if !ok {
+		return fmt.Errorf("expected plan to be a module, got %s", plan.Type())
+	}
+
+	for name, member := range testPlan.planModule.Members {
+		builtin, ok := member.(*starlark.Builtin)
+		if !ok {
+			continue
+		}
+
+		planModule.Members[name] = starlark.NewBuiltin(name, testPlan.createInstructionBuiltin(builtin))
+	}
+
+	return nil
+}
+
+// Instructions returns the instructions added to the plan so far, in the order they were added
+func (testPlan *TestPlan) Instructions() []*TestPlanInstruction {
+	testPlan.mutex.Lock()
+	defer testPlan.mutex.Unlock()
+
+	return append([]*TestPlanInstruction{}, testPlan.instructions...)
+}
+
+// Snapshot returns a stable textual representation of the instructions added to the plan so far, one per line
+//
+// The random runtime value UUIDs are replaced with sequential ones so that the snapshot does not change between runs
+func (testPlan *TestPlan) Snapshot() string {
+	runtimeValueUuids := map[string]string{}
+	normalizeRuntimeValueUuid := func(match string) string {
+		uuid := runtimeValueUuidRegexp.FindStringSubmatch(match)[1]
+		if _, ok := runtimeValueUuids[uuid]; !ok {
+			runtimeValueUuids[uuid] = fmt.Sprintf("runtime-value-%d", len(runtimeValueUuids)+1)
+		}
+
+		return fmt.Sprintf("{{kurtosis:%s:", runtimeValueUuids[uuid])
+	}
+
+	var snapshot strings.Builder
+	for _, instruction := range testPlan.Instructions() {
+		snapshot.WriteString(runtimeValueUuidRegexp.ReplaceAllStringFunc(instruction.Instruction.String(), normalizeRuntimeValueUuid))
+		snapshot.WriteString("\n")
+	}
+
+	return snapshot.String()
+}
+
+func (testPlan *TestPlan) createInstructionBuiltin(builtin *starlark.Builtin) func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		// The original builtin is called directly, without adding a frame to the call stack,
+		// since kurtosis uses the call stack to resolve relative paths passed to the instructions
+		value, err := builtin.CallInternal(thread, args, kwargs)
+		if err != nil {
+			return nil, err
+		}
+
+		err = testPlan.collectPending(builtin.Name(), args, kwargs)
+		if err != nil {
+			return nil, err
+		}
+
+		return value, nil
+	}
+}
+
+// Records all the instructions that have been added to the instructions plan since the last call
+// and executes them if the plan is being executed
+func (testPlan *TestPlan) collectPending(name string, args starlark.Tuple, kwargs []starlark.Tuple) error {
+	testPlan.mutex.Lock()
+	defer testPlan.mutex.Unlock()
+
+	scheduledInstructions, err := testPlan.instructionsPlan.GeneratePlan()
+	if err != nil {
+		return err
+	}
+
+	for _, scheduledInstruction := range scheduledInstructions[len(testPlan.instructions):] {
+		testPlan.instructions = append(testPlan.instructions, &TestPlanInstruction{
+			Name:        name,
+			Args:        args,
+			Kwargs:      kwargs,
+			Instruction: scheduledInstruction.GetInstruction(),
+		})
+	}
+
+	if !testPlan.execute {
+		return nil
+	}
+
+	for _, instruction := range testPlan.instructions[testPlan.executed:] {
+		testPlan.executed++
+
+		output, err := instruction.Instruction.Execute(testPlan.ctx)
+		if err != nil {
+			return fmt.Errorf("failed to execute %s: %v", instruction.Instruction.String(), err)
+		}
+
+		if output != nil {
+			logrus.Debugf("Executed %s: %s", instruction.Instruction.String(), *output)
+		}
+	}
+
+	return nil
+}
@@ -2,15 +2,18 @@ package kurtosis
 
 import (
 	"fmt"
+	"kurtestosis/cli/core"
+	"kurtestosis/cli/kurtosis/backend"
 	"kurtestosis/cli/kurtosis/modules"
 
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/builtins/import_module"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/interpretation_time_value_store"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarktest"
 )
 
-func LoadKurtestosisPredeclared(interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore) (starlark.StringDict, error) {
+func LoadKurtestosisPredeclared(interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore, serviceNetwork *backend.KurtestosisServiceNetwork, testPlan *backend.TestPlan, moduleReplacements *backend.ModuleReplacements, snapshots *core.Snapshots, reporter *core.TestReporter, limiter *ThreadLimiter) (starlark.StringDict, error) {
 	var err error
 
 	assertPredeclared, err := starlarktest.LoadAssertModule()
@@ -23,7 +26,22 @@ func LoadKurtestosisPredeclared(interpretationTimeValueStore *interpretation_tim
 		"expect": assertPredeclared["assert"],
 	}
 
-	kurtestosisPredeclared, err := modules.LoadKurtestosisModule(interpretationTimeValueStore)
+	kurtestosisPredeclared, err := modules.LoadKurtestosisModule(interpretationTimeValueStore, serviceNetwork, testPlan, moduleReplacements, snapshots, reporter, modules.KurtestosisHooks{
+		BeforeTest: func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) error {
+			// The starlarktest assert module requires a reporter to be set on the thread that runs the test
+			starlarktest.SetReporter(thread, reporter)
+
+			// The test itself runs on a separate thread that also needs to be limited
+			limiter.Track(thread)
+
+			// The test needs to get a plan that collects (and possibly executes) the instructions it adds
+			if len(args) > 0 {
+				return testPlan.Attach(args[0])
+			}
+
+			return nil
+		},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to load assert kurtestosis: %v", err)
 	}
@@ -31,16 +49,22 @@ func LoadKurtestosisPredeclared(interpretationTimeValueStore *interpretation_tim
 	return MergeDicts(assertPredeclared, expectPredeclared, kurtestosisPredeclared), nil
 }
 
-func CreateProcessBuiltins(extraPredeclared starlark.StringDict) startosis_engine.StartosisInterpreterBuiltinsProcessor {
+// CreateProcessBuiltins creates a processor that adds extraPredeclared builtins to every module
+//
+// The processor gets called right after the interpreter builds the predeclared builtins of a module
+// so it needs to release the predeclared lock for the other interpreters.
+// Since the processor gets called for every module loading thread, the threads are also passed to the limiter.
+// The import_module builtin is wrapped so that it resolves the modules replaced by the test
+func CreateProcessBuiltins(extraPredeclared starlark.StringDict, moduleReplacements *backend.ModuleReplacements, limiter *ThreadLimiter, predeclaredLock *backend.PredeclaredLock) startosis_engine.StartosisInterpreterBuiltinsProcessor {
 	return func(thread *starlark.Thread, predeclared starlark.StringDict) starlark.StringDict {
-		return MergeDicts(predeclared, extraPredeclared)
-	}
-}
+		predeclared = predeclaredLock.Release(predeclared)
 
-func SetupKurtestosisPredeclared(reporter starlarktest.Reporter) {
-	modules.SetBeforeTestFunction(func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) error {
-		starlarktest.SetReporter(thread, reporter)
+		limiter.Track(thread)
 
-		return nil
-	})
+		if importModule, ok := predeclared[import_module.ImportModuleBuiltinName].(*starlark.Builtin); ok {
+			predeclared[import_module.ImportModuleBuiltinName] = moduleReplacements.WrapImportModule(importModule)
+		}
+
+		return MergeDicts(predeclared, extraPredeclared)
+	}
 }
@@ -0,0 +1,26 @@
+package kurtosis
+
+import (
+	"kurtestosis/cli/core"
+
+	"go.starlark.net/starlark"
+)
+
+// LoadCoveragePredeclared creates the builtin called by the code instrumented using core.InstrumentModule
+func LoadCoveragePredeclared(coverage *core.Coverage) starlark.StringDict {
+	return starlark.StringDict{
+		core.CoverageBuiltinName: starlark.NewBuiltin(core.CoverageBuiltinName, func(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var file string
+			var line int
+			var value starlark.Value = starlark.True
+			err := starlark.UnpackPositionalArgs(builtin.Name(), args, kwargs, 2, &file, &line, &value)
+			if err != nil {
+				return nil, err
+			}
+
+			coverage.Hit(file, line)
+
+			return value, nil
+		}),
+	}
+}
@@ -0,0 +1,56 @@
+package kurtosis
+
+import (
+	"sync"
+
+	"go.starlark.net/starlark"
+)
+
+// ThreadLimiter enforces execution limits on all the starlark threads that run a test
+//
+// Kurtosis interpreter creates a new thread for every loaded module and another one for the main function
+// so every one of these threads needs to be tracked in order to be able to stop the test
+type ThreadLimiter struct {
+	// Maximum number of starlark execution steps per thread, 0 means unlimited
+	maxSteps uint64
+
+	mutex        sync.Mutex
+	threads      []*starlark.Thread
+	cancelled    bool
+	cancelReason string
+}
+
+func NewThreadLimiter(maxSteps uint64) *ThreadLimiter {
+	return &ThreadLimiter{
+		maxSteps: maxSteps,
+	}
+}
+
+// Track applies the step budget to a thread and makes sure it will be stopped by Cancel
+//
+// Threads tracked after Cancel has been called get cancelled right away
+func (limiter *ThreadLimiter) Track(thread *starlark.Thread) {
+	if limiter.maxSteps > 0 {
+		thread.SetMaxExecutionSteps(limiter.maxSteps)
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.threads = append(limiter.threads, thread)
+	if limiter.cancelled {
+		thread.Cancel(limiter.cancelReason)
+	}
+}
+
+// Cancel stops all the tracked threads at their next execution step
+func (limiter *ThreadLimiter) Cancel(reason string) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.cancelled = true
+	limiter.cancelReason = reason
+	for _, thread := range limiter.threads {
+		thread.Cancel(reason)
+	}
+}
@@ -0,0 +1,94 @@
+package kurtosis
+
+import (
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+// Runs forever unless the thread gets stopped
+const infiniteLoopSrc = `
+def loop():
+    for i in range(1000000000000):
+        pass
+
+loop()
+`
+
+func TestThreadLimiterMaxSteps(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxSteps      uint64
+		src           string
+		expectedError string
+	}{
+		{
+			name:     "within the budget",
+			maxSteps: 1000,
+			src:      "x = [i for i in range(10)]\n",
+		},
+		{
+			name:          "over the budget",
+			maxSteps:      1000,
+			src:           infiniteLoopSrc,
+			expectedError: "too many steps",
+		},
+		{
+			name:     "unlimited",
+			maxSteps: 0,
+			src:      "x = [i for i in range(100000)]\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := NewThreadLimiter(test.maxSteps)
+			thread := &starlark.Thread{}
+			limiter.Track(thread)
+
+			_, err := starlark.ExecFile(thread, "main.star", test.src, starlark.StringDict{})
+			if test.expectedError == "" {
+				if err != nil {
+					t.Fatalf("expected the thread to finish, got %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Fatalf("expected an error containing %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestThreadLimiterCancel(t *testing.T) {
+	limiter := NewThreadLimiter(0)
+
+	// A thread tracked before Cancel is stopped at its next step
+	runningThread := &starlark.Thread{}
+	limiter.Track(runningThread)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := starlark.ExecFile(runningThread, "main.star", infiniteLoopSrc, starlark.StringDict{})
+		errs <- err
+	}()
+
+	limiter.Cancel("test timed out")
+
+	err := <-errs
+	if err == nil || !strings.Contains(err.Error(), "test timed out") {
+		t.Errorf("expected the running thread to be cancelled, got %v", err)
+	}
+
+	// A thread tracked after Cancel is stopped right away
+	laterThread := &starlark.Thread{}
+	limiter.Track(laterThread)
+
+	_, err = starlark.ExecFile(laterThread, "main.star", "x = 1\n", starlark.StringDict{})
+	if err == nil || !strings.Contains(err.Error(), "test timed out") {
+		t.Errorf("expected the thread tracked after Cancel to be cancelled, got %v", err)
+	}
+}
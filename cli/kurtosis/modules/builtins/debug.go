@@ -1,11 +1,13 @@
 package builtins
 
 import (
+	"fmt"
+	"kurtestosis/cli/core"
+
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
-	"github.com/sirupsen/logrus"
 	"go.starlark.net/starlark"
 )
 
@@ -15,7 +17,7 @@ const (
 	DebugBuiltinValueArgName = "value"
 )
 
-func NewDebug() *kurtosis_helper.KurtosisHelper {
+func NewDebug(reporter *core.TestReporter) *kurtosis_helper.KurtosisHelper {
 	return &kurtosis_helper.KurtosisHelper{
 		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
 			Name: DebugBuiltinName,
@@ -31,11 +33,15 @@ func NewDebug() *kurtosis_helper.KurtosisHelper {
 			},
 		},
 
-		Capabilities: &debugCapabilities{},
+		Capabilities: &debugCapabilities{
+			reporter: reporter,
+		},
 	}
 }
 
-type debugCapabilities struct{}
+type debugCapabilities struct {
+	reporter *core.TestReporter
+}
 
 func (builtin *debugCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
 	valueArg, err := builtin_argument.ExtractArgumentValue[starlark.Value](arguments, DebugBuiltinValueArgName)
@@ -43,7 +49,7 @@ func (builtin *debugCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsB
 		return nil, startosis_errors.WrapWithInterpretationError(err, "An error occurred while extracting the value argument for debug builtin")
 	}
 
-	logrus.Infof("%s: %s", locatorOfModuleInWhichThisBuiltInIsBeingCalled, valueArg)
+	builtin.reporter.Log(fmt.Sprintf("%s: %s", locatorOfModuleInWhichThisBuiltInIsBeingCalled, valueArg))
 
 	return starlark.None, nil
 }
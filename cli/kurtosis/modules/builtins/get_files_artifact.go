@@ -0,0 +1,67 @@
+package builtins
+
+import (
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	GetFilesArtifactBuiltinName = "get_files_artifact"
+
+	FilesArtifactNameArgName = "name"
+)
+
+func NewGetFilesArtifact(serviceNetwork *backend.KurtestosisServiceNetwork) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: GetFilesArtifactBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              FilesArtifactNameArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, FilesArtifactNameArgName)
+					},
+				},
+			},
+		},
+
+		Capabilities: &getFilesArtifactCapabilities{
+			serviceNetwork: serviceNetwork,
+		},
+	}
+}
+
+type getFilesArtifactCapabilities struct {
+	serviceNetwork *backend.KurtestosisServiceNetwork
+}
+
+func (builtin *getFilesArtifactCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	nameArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, FilesArtifactNameArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	// Files artifacts are only stored once the instructions that create them get executed
+	artifact, exists := builtin.serviceNetwork.GetFilesArtifact(nameArgValue.GoString())
+	if !exists {
+		return nil, startosis_errors.NewInterpretationError("Files artifact %s does not exist, make sure the test is executing the plan", nameArgValue.GoString())
+	}
+
+	// The files are added in alphabetical order so that the dict is printed the same way every time
+	files := starlark.NewDict(len(artifact.Files))
+	for _, path := range artifact.Paths() {
+		err := files.SetKey(starlark.String(path), starlark.String(artifact.Files[path]))
+		if err != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(err, "failed to set key")
+		}
+	}
+
+	return files, nil
+}
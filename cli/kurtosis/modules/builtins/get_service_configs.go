@@ -0,0 +1,61 @@
+package builtins
+
+import (
+	"kurtestosis/cli/core"
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/interpretation_time_value_store"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	GetServiceConfigsBuiltinName = "get_service_configs"
+)
+
+func NewGetServiceConfigs(
+	interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore,
+	testPlan *backend.TestPlan,
+	project *core.KurtestosisProject,
+) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name:      GetServiceConfigsBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{},
+		},
+
+		Capabilities: &getServiceConfigsCapabilities{
+			interpretationTimeValueStore: interpretationTimeValueStore,
+			testPlan:                     testPlan,
+			project:                      project,
+		},
+	}
+}
+
+type getServiceConfigsCapabilities struct {
+	interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore
+	testPlan                     *backend.TestPlan
+	project                      *core.KurtestosisProject
+}
+
+func (builtin *getServiceConfigsCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	serviceNames := registeredServiceNames(builtin.testPlan)
+
+	serviceConfigs := starlark.NewDict(len(serviceNames))
+	for _, serviceName := range serviceNames {
+		serviceConfig, interpretationErr := getStarlarkServiceConfig(builtin.interpretationTimeValueStore, builtin.testPlan, builtin.project, serviceName)
+		if interpretationErr != nil {
+			return nil, interpretationErr
+		}
+
+		err := serviceConfigs.SetKey(starlark.String(serviceName), serviceConfig)
+		if err != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(err, "failed to set key")
+		}
+	}
+
+	return serviceConfigs, nil
+}
@@ -0,0 +1,78 @@
+package builtins
+
+import (
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	HttpRequestsBuiltinName = "http_requests"
+)
+
+func NewHttpRequests(serviceNetwork *backend.KurtestosisServiceNetwork) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: HttpRequestsBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              ServiceNameArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, ServiceNameArgName)
+					},
+				},
+			},
+		},
+
+		Capabilities: &httpRequestsCapabilities{
+			serviceNetwork: serviceNetwork,
+		},
+	}
+}
+
+type httpRequestsCapabilities struct {
+	serviceNetwork *backend.KurtestosisServiceNetwork
+}
+
+func (builtin *httpRequestsCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	serviceName := ""
+	if arguments.IsSet(ServiceNameArgName) {
+		serviceNameArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, ServiceNameArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		serviceName = serviceNameArgValue.GoString()
+	}
+
+	requests := []starlark.Value{}
+	for _, request := range builtin.serviceNetwork.HttpRequests() {
+		if serviceName != "" && string(request.ServiceName) != serviceName {
+			continue
+		}
+
+		headers, interpretationErr := stringMapToStarlarkDict(request.Headers)
+		if interpretationErr != nil {
+			return nil, interpretationErr
+		}
+
+		requests = append(requests, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"service_name": starlark.String(request.ServiceName),
+			"port_id":      starlark.String(request.PortId),
+			"method":       starlark.String(request.Method),
+			"endpoint":     starlark.String(request.Endpoint),
+			"content_type": starlark.String(request.ContentType),
+			"body":         starlark.String(request.Body),
+			"headers":      headers,
+		}))
+	}
+
+	return starlark.NewList(requests), nil
+}
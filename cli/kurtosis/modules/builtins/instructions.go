@@ -0,0 +1,62 @@
+package builtins
+
+import (
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	InstructionsBuiltinName = "instructions"
+)
+
+func NewInstructions(testPlan *backend.TestPlan) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name:      InstructionsBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{},
+		},
+
+		Capabilities: &instructionsCapabilities{
+			testPlan: testPlan,
+		},
+	}
+}
+
+type instructionsCapabilities struct {
+	testPlan *backend.TestPlan
+}
+
+func (builtin *instructionsCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	instructions := []starlark.Value{}
+	for _, instruction := range builtin.testPlan.Instructions() {
+		kwargs := starlark.NewDict(len(instruction.Kwargs))
+		for _, kwarg := range instruction.Kwargs {
+			err := kwargs.SetKey(kwarg[0], kwarg[1])
+			if err != nil {
+				return nil, startosis_errors.WrapWithInterpretationError(err, "failed to set key")
+			}
+		}
+
+		// Only the filename has a getter, the line and column are only exposed via the API type
+		position := instruction.Instruction.GetPositionInOriginalScript().ToAPIType()
+
+		instructions = append(instructions, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"name":   starlark.String(instruction.Name),
+			"args":   instruction.Args,
+			"kwargs": kwargs,
+			"position": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+				"filename": starlark.String(position.GetFilename()),
+				"line":     starlark.MakeInt(int(position.GetLine())),
+				"col":      starlark.MakeInt(int(position.GetColumn())),
+			}),
+		}))
+	}
+
+	return starlark.NewList(instructions), nil
+}
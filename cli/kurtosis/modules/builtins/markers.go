@@ -0,0 +1,86 @@
+package builtins
+
+import (
+	"kurtestosis/cli/core"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	SkipBuiltinName  = "skip"
+	XFailBuiltinName = "xfail"
+
+	MarkerBuiltinReasonArgName = "reason"
+)
+
+// NewSkip creates the skip builtin, the runtime equivalent of the skip pragma
+func NewSkip(reporter *core.TestReporter) *kurtosis_helper.KurtosisHelper {
+	return newMarker(SkipBuiltinName, core.TestStatusSkip, reporter)
+}
+
+// NewXFail creates the xfail builtin, the runtime equivalent of the xfail pragma
+func NewXFail(reporter *core.TestReporter) *kurtosis_helper.KurtosisHelper {
+	return newMarker(XFailBuiltinName, core.TestStatusXFail, reporter)
+}
+
+// Creates a builtin that marks the running test function with a status, along with an optional reason
+func newMarker(name string, status core.TestStatus, reporter *core.TestReporter) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: name,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              MarkerBuiltinReasonArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+			},
+		},
+
+		Capabilities: &markerCapabilities{
+			name:     name,
+			status:   status,
+			reporter: reporter,
+		},
+	}
+}
+
+type markerCapabilities struct {
+	name     string
+	status   core.TestStatus
+	reporter *core.TestReporter
+}
+
+func (builtin *markerCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	reason := ""
+	if arguments.IsSet(MarkerBuiltinReasonArgName) {
+		reasonArg, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, MarkerBuiltinReasonArgName)
+		if err != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(err, "An error occurred while extracting the reason argument for %s builtin", builtin.name)
+		}
+
+		reason = reasonArg.GoString()
+	}
+
+	switch builtin.status {
+	case core.TestStatusSkip:
+		// Starlark has no other way of stopping the execution so we need to return an error.
+		// The test runner ignores this error, but not any other error the test function runs into
+		skipErr := startosis_errors.WrapWithInterpretationError(core.ErrTestSkipped, "%s: %s", builtin.name, reason)
+		builtin.reporter.SkipWithError(reason, skipErr)
+
+		return nil, skipErr
+	case core.TestStatusXFail:
+		// The test keeps running, its errors will be reported as an expected failure
+		builtin.reporter.ExpectFailure(reason)
+	}
+
+	return starlark.None, nil
+}
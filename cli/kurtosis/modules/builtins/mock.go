@@ -1,11 +1,11 @@
 package builtins
 
 import (
-	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
-	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
-	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
-	"github.com/sirupsen/logrus"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarkstruct"
 )
@@ -22,84 +22,196 @@ type starlarkCallable interface {
 	CallInternal(thread *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error)
 }
 
-func NewMock() *kurtosis_helper.KurtosisHelper {
-	return &kurtosis_helper.KurtosisHelper{
-		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
-			Name: MockBuiltinName,
-			Arguments: []*builtin_argument.BuiltinArgument{
-				{
-					Name:              MockBuiltinTargetArgName,
-					IsOptional:        false,
-					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.Value],
-					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
-						// TODO
-						return nil
-					},
-				},
-				{
-					Name:              MockBuiltinMethodNameArgName,
-					IsOptional:        false,
-					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
-					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
-						return builtin_argument.NonEmptyString(value, ServiceNameArgName)
-					},
-				},
-			},
-		},
-
-		Capabilities: &mockCapabilities{},
+// NewMock creates the mock builtin
+//
+// Unlike the other builtins, mock is not a kurtosis helper since those get passed deep copies of their arguments
+// while the mock needs to replace the method on the original target
+func NewMock() func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var targetArg starlark.Value
+		var methodNameArg starlark.String
+		err := starlark.UnpackArgs(b.Name(), args, kwargs, MockBuiltinTargetArgName, &targetArg, MockBuiltinMethodNameArgName, &methodNameArg)
+		if err != nil {
+			return nil, err
+		}
+
+		if methodNameArg.GoString() == "" {
+			return nil, startosis_errors.NewInterpretationError("mock: %s cannot be empty", MockBuiltinMethodNameArgName)
+		}
+
+		return mockTargetMethod(targetArg, methodNameArg.GoString())
 	}
 }
 
-type mockCapabilities struct{}
-
-func (builtin *mockCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
-	// Since we are trying to extract a module passed as an argument, we'd always get a warning
-	// about the fact that module cannot be copied
-	//
-	// It's something we know so let's just suppress the warning
-	logLevel := logrus.GetLevel()
-	logrus.SetLevel(logrus.ErrorLevel)
-	targetArg, _ := builtin_argument.ExtractArgumentValue[starlark.Value](arguments, MockBuiltinTargetArgName)
-	logrus.SetLevel(logLevel)
+func mockTargetMethod(targetArg starlark.Value, methodNamePath string) (starlark.Value, error) {
+	// The method name can be a dotted path to a method nested in modules, structs or dicts, e.g. helpers.launch
+	path := strings.Split(methodNamePath, ".")
 
-	target, ok := targetArg.(*starlarkstruct.Module)
-	if !ok {
-		return nil, startosis_errors.NewInterpretationError("mock: only module mocks are possible at the moment. %v is not a module", targetArg)
+	targets, interpretationErr := getMockTargets(targetArg, path)
+	if interpretationErr != nil {
+		return nil, interpretationErr
 	}
 
-	// We need to make sure that the module has a property called methodName
-	methodNameArg, _ := builtin_argument.ExtractArgumentValue[starlark.String](arguments, MockBuiltinMethodNameArgName)
-	methodName := methodNameArg.GoString()
-	if !target.Members.Has(methodName) {
-		return nil, startosis_errors.NewInterpretationError("mock: module %v doesn't have a property called %s", targetArg, methodName)
+	// We need to make sure that the target has a property called methodName
+	target := targets[len(targets)-1]
+	methodName := path[len(path)-1]
+	targetValue, interpretationErr := getMockTargetMember(target, methodName)
+	if interpretationErr != nil {
+		return nil, interpretationErr
 	}
 
 	// Now we have to make sure that the property under methodName is a callable function
 	var targetMethod starlarkCallable
-	targetValue := target.Members[methodName]
+	var ok bool
 	targetMethod, ok = targetValue.(*starlark.Builtin)
 	if !ok {
 		targetMethod, ok = targetValue.(*starlark.Function)
 		if !ok {
-			return nil, startosis_errors.NewInterpretationError("mock: property %s of module %v is not a function, it's %v", methodName, targetArg, targetValue)
+			return nil, startosis_errors.NewInterpretationError("mock: property %s of %s %v is not a function, it's %v", methodName, target.Type(), target, targetValue)
 		}
 	}
 
-	mock, mockedMethod := createMock(targetMethod)
+	mock, mockedMethod := createMock(targetMethod, func() error {
+		// The containers along the path might have been replaced since the method was mocked, so we look them up again
+		targets, interpretationErr := getMockTargets(targetArg, path)
+		if interpretationErr != nil {
+			return interpretationErr
+		}
 
-	// TODO Add a restore() functionality to the mock
-	target.Members[methodName] = mockedMethod
+		return setMockTargetMember(targets, path, targetValue)
+	})
+
+	err := setMockTargetMember(targets, path, mockedMethod)
+	if err != nil {
+		return nil, startosis_errors.WrapWithInterpretationError(err, "mock: failed to replace property %s of %s %v", methodName, target.Type(), target)
+	}
 
 	return mock, nil
 }
 
-func createMock(originalMethod starlarkCallable) (mock *starlarkstruct.Struct, mockedMethod *starlark.Builtin) {
+// Returns the modules, structs or dicts along a dotted path, starting with the target itself
+// and ending with the one holding the method
+func getMockTargets(target starlark.Value, path []string) ([]starlark.Value, *startosis_errors.InterpretationError) {
+	targets := []starlark.Value{target}
+	for _, name := range path[:len(path)-1] {
+		member, interpretationErr := getMockTargetMember(target, name)
+		if interpretationErr != nil {
+			return nil, interpretationErr
+		}
+
+		target = member
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// Returns a member of a module, a struct or a dict that can be mocked
+func getMockTargetMember(target starlark.Value, name string) (starlark.Value, *startosis_errors.InterpretationError) {
+	var member starlark.Value
+	var found bool
+
+	switch target := target.(type) {
+	case *starlarkstruct.Module:
+		member, found = target.Members[name]
+	case *starlarkstruct.Struct:
+		member, _ = target.Attr(name)
+		found = member != nil
+	case *starlark.Dict:
+		member, found, _ = target.Get(starlark.String(name))
+	default:
+		return nil, startosis_errors.NewInterpretationError("mock: only module, struct and dict mocks are possible. %v is a %s", target, target.Type())
+	}
+
+	if !found {
+		return nil, startosis_errors.NewInterpretationError("mock: %s %v doesn't have a property called %s", target.Type(), target, name)
+	}
+
+	return member, nil
+}
+
+// Replaces the member at the end of a dotted path
+//
+// Structs are immutable and dicts defined in a module are frozen once the module has been loaded,
+// so these are replaced by copies holding the new member, up to the closest module or unfrozen dict along the path.
+// A struct or a frozen dict passed as the target itself has nowhere to put its copy, so it cannot be mocked
+func setMockTargetMember(targets []starlark.Value, path []string, value starlark.Value) error {
+	for index := len(targets) - 1; index >= 0; index-- {
+		target, name := targets[index], path[index]
+
+		switch target := target.(type) {
+		case *starlarkstruct.Module:
+			target.Members[name] = value
+
+			return nil
+		case *starlark.Dict:
+			if target.SetKey(starlark.String(name), value) == nil {
+				return nil
+			}
+		}
+
+		if index == 0 {
+			return startosis_errors.NewInterpretationError("mock: %s %v cannot be changed, pass the module that holds it as the %s and the path to the function as the %s instead, e.g. mock(module, \"%s_name.%s\")", target.Type(), target, MockBuiltinTargetArgName, MockBuiltinMethodNameArgName, target.Type(), path[len(path)-1])
+		}
+
+		copiedTarget, err := copyMockTarget(target, name, value)
+		if err != nil {
+			return err
+		}
+
+		value = copiedTarget
+	}
+
+	return nil
+}
+
+// Returns a frozen copy of a struct or a dict with one of its members replaced
+func copyMockTarget(target starlark.Value, name string, value starlark.Value) (starlark.Value, error) {
+	switch target := target.(type) {
+	case *starlarkstruct.Struct:
+		members := starlark.StringDict{}
+		target.ToStringDict(members)
+		members[name] = value
+
+		copiedStruct := starlarkstruct.FromStringDict(target.Constructor(), members)
+		copiedStruct.Freeze()
+
+		return copiedStruct, nil
+	case *starlark.Dict:
+		copiedDict := starlark.NewDict(target.Len())
+		for _, item := range target.Items() {
+			err := copiedDict.SetKey(item[0], item[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := copiedDict.SetKey(starlark.String(name), value)
+		if err != nil {
+			return nil, err
+		}
+
+		copiedDict.Freeze()
+
+		return copiedDict, nil
+	}
+
+	return nil, fmt.Errorf("cannot copy %s %v", target.Type(), target)
+}
+
+// Creates a mock struct along with the mocked method that needs to replace the original one
+//
+// The restore function is called to put the original method back in place
+func createMock(originalMethod starlarkCallable, restore func() error) (mock *starlarkstruct.Struct, mockedMethod *starlark.Builtin) {
 	// This will hold an array of structs representing each call to the mocked method
 	calls := []starlark.Value{}
 
-	// return values can be mocked, in which case this will be set to a non-nil value
-	var mockReturnValue starlark.Value
+	// The mocked method can be made to return a value, call another function or fail,
+	// in which case this will be set to a non-nil function. Otherwise the original method is called
+	var mockBehavior func(thread *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error)
+
+	// Return values mocked for a single call, these take precedence over the mock behavior
+	returnValuesOnce := []starlark.Value{}
 
 	mockedMethod = starlark.NewBuiltin(MockBuiltinName, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 		// First we turn the mocked method args/kwargs into starlark values
@@ -111,18 +223,26 @@ func createMock(originalMethod starlarkCallable) (mock *starlarkstruct.Struct, m
 
 		// Now we handle the return value
 		var returnValue starlark.Value
+		var callErr error
 
-		if mockReturnValue != nil {
-			// If it's mocked then we just return the mocked value
-			returnValue = mockReturnValue
+		if len(returnValuesOnce) > 0 {
+			// Return values mocked once are used up in the order they were mocked
+			returnValue, returnValuesOnce = returnValuesOnce[0], returnValuesOnce[1:]
+		} else if mockBehavior != nil {
+			returnValue, callErr = mockBehavior(thread, args, kwargs)
 		} else {
-			// If it's not we need to call the original method
+			// If it's not mocked we need to call the original method
 			returnValue, err = originalMethod.CallInternal(thread, args, kwargs)
 			if err != nil {
 				return nil, startosis_errors.WrapWithInterpretationError(err, "failed to call original method")
 			}
 		}
 
+		// A failed call has no return value
+		if callErr != nil {
+			returnValue = starlark.None
+		}
+
 		// We create a struct representing this call
 		calls = append(calls, starlarkstruct.FromStringDict(starlarkstruct.Default, map[string]starlark.Value{
 			"args":         callArgs,
@@ -130,6 +250,10 @@ func createMock(originalMethod starlarkCallable) (mock *starlarkstruct.Struct, m
 			"return_value": returnValue,
 		}))
 
+		if callErr != nil {
+			return nil, callErr
+		}
+
 		return returnValue, nil
 	})
 
@@ -138,15 +262,107 @@ func createMock(originalMethod starlarkCallable) (mock *starlarkstruct.Struct, m
 		"calls": starlark.NewBuiltin("calls", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 			return starlark.NewList(calls), nil
 		}),
+		"call_count": starlark.NewBuiltin("call_count", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			return starlark.MakeInt(len(calls)), nil
+		}),
 		"mock_return_value": starlark.NewBuiltin("mock_return_value", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 			if args.Len() == 0 {
-				mockReturnValue = nil
+				mockBehavior = nil
+			} else {
+				mockReturnValue := args.Index(0)
+				mockBehavior = func(thread *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+					return mockReturnValue, nil
+				}
+			}
+
+			return mock, nil
+		}),
+		"mock_return_value_once": starlark.NewBuiltin("mock_return_value_once", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var returnValue starlark.Value
+			err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &returnValue)
+			if err != nil {
+				return nil, err
+			}
+
+			returnValuesOnce = append(returnValuesOnce, returnValue)
+
+			return mock, nil
+		}),
+		"mock_implementation": starlark.NewBuiltin("mock_implementation", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var implementation starlark.Callable
+			err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0, &implementation)
+			if err != nil {
+				return nil, err
+			}
+
+			if implementation == nil {
+				mockBehavior = nil
 			} else {
-				mockReturnValue = args.Index(0)
+				mockBehavior = func(thread *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+					return starlark.Call(thread, implementation, args, kwargs)
+				}
 			}
 
 			return mock, nil
 		}),
+		"mock_error": starlark.NewBuiltin("mock_error", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var message string
+			err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &message)
+			if err != nil {
+				return nil, err
+			}
+
+			mockBehavior = func(thread *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				return nil, errors.New(message)
+			}
+
+			return mock, nil
+		}),
+		"reset": starlark.NewBuiltin("reset", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			calls = []starlark.Value{}
+			returnValuesOnce = []starlark.Value{}
+			mockBehavior = nil
+
+			return mock, nil
+		}),
+		"restore": starlark.NewBuiltin("restore", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			err := restore()
+			if err != nil {
+				return nil, err
+			}
+
+			return starlark.None, nil
+		}),
+		"assert_called_with": starlark.NewBuiltin("assert_called_with", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if len(calls) == 0 {
+				return nil, fmt.Errorf("mock: expected a call with %s but the mock has not been called", formatCall(args, kwargs))
+			}
+
+			expectedKwargs, err := kwargsToDict(kwargs)
+			if err != nil {
+				return nil, err
+			}
+
+			lastCall := calls[len(calls)-1].(*starlarkstruct.Struct)
+			lastCallArgs, _ := lastCall.Attr("args")
+			lastCallKwargs, _ := lastCall.Attr("kwargs")
+
+			argsEqual, err := starlark.Equal(lastCallArgs, starlark.NewList(args))
+			if err != nil {
+				return nil, err
+			}
+
+			kwargsEqual, err := starlark.Equal(lastCallKwargs, expectedKwargs)
+			if err != nil {
+				return nil, err
+			}
+
+			if !argsEqual || !kwargsEqual {
+				return nil, fmt.Errorf("mock: expected the last call to be %s, got %s", formatCall(args, kwargs), formatCall(starlark.Tuple(listToSlice(lastCallArgs.(*starlark.List))), dictToKwargs(lastCallKwargs.(*starlark.Dict))))
+			}
+
+			return starlark.None, nil
+		}),
 	}
 
 	mock = starlarkstruct.FromStringDict(starlark.String(MockBuiltinStructName), mockMembers)
@@ -154,6 +370,38 @@ func createMock(originalMethod starlarkCallable) (mock *starlarkstruct.Struct, m
 	return mock, mockedMethod
 }
 
+// Formats call arguments the way they would be written in starlark, e.g. (1, "two", three = 3)
+func formatCall(args starlark.Tuple, kwargs []starlark.Tuple) string {
+	formattedArgs := []string{}
+	for _, arg := range args {
+		formattedArgs = append(formattedArgs, arg.String())
+	}
+
+	for _, kwarg := range kwargs {
+		formattedArgs = append(formattedArgs, fmt.Sprintf("%s = %s", kwarg[0].(starlark.String).GoString(), kwarg[1].String()))
+	}
+
+	return "(" + strings.Join(formattedArgs, ", ") + ")"
+}
+
+func listToSlice(list *starlark.List) []starlark.Value {
+	values := make([]starlark.Value, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		values = append(values, list.Index(i))
+	}
+
+	return values
+}
+
+func dictToKwargs(dict *starlark.Dict) []starlark.Tuple {
+	kwargs := make([]starlark.Tuple, 0, dict.Len())
+	for _, item := range dict.Items() {
+		kwargs = append(kwargs, item)
+	}
+
+	return kwargs
+}
+
 func kwargsToDict(kwargs []starlark.Tuple) (*starlark.Dict, error) {
 	dict := starlark.NewDict(len(kwargs))
 	for _, kwarg := range kwargs {
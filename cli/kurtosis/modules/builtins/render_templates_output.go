@@ -0,0 +1,86 @@
+package builtins
+
+import (
+	"strings"
+
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	RenderTemplatesOutputBuiltinName = "render_templates_output"
+
+	RenderTemplatesOutputArtifactNameArgName = "artifact_name"
+	RenderTemplatesOutputPathArgName         = "path"
+)
+
+func NewRenderTemplatesOutput(serviceNetwork *backend.KurtestosisServiceNetwork) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: RenderTemplatesOutputBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              RenderTemplatesOutputArtifactNameArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, RenderTemplatesOutputArtifactNameArgName)
+					},
+				},
+				{
+					Name:              RenderTemplatesOutputPathArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, RenderTemplatesOutputPathArgName)
+					},
+				},
+			},
+		},
+
+		Capabilities: &renderTemplatesOutputCapabilities{
+			serviceNetwork: serviceNetwork,
+		},
+	}
+}
+
+type renderTemplatesOutputCapabilities struct {
+	serviceNetwork *backend.KurtestosisServiceNetwork
+}
+
+func (builtin *renderTemplatesOutputCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	artifactNameArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, RenderTemplatesOutputArtifactNameArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	pathArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, RenderTemplatesOutputPathArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	artifactName := artifactNameArgValue.GoString()
+	path := pathArgValue.GoString()
+
+	// Templates are only rendered once the render_templates instruction gets executed
+	artifact, exists := builtin.serviceNetwork.GetFilesArtifact(artifactName)
+	if !exists {
+		return nil, startosis_errors.NewInterpretationError("Files artifact %s does not exist, make sure the test is executing the plan", artifactName)
+	}
+
+	if !artifact.Rendered {
+		return nil, startosis_errors.NewInterpretationError("Files artifact %s was not created by render_templates", artifactName)
+	}
+
+	content, exists := artifact.Files[path]
+	if !exists {
+		return nil, startosis_errors.NewInterpretationError("Files artifact %s has no rendered template %s, rendered templates: %s", artifactName, path, strings.Join(artifact.Paths(), ", "))
+	}
+
+	return starlark.String(content), nil
+}
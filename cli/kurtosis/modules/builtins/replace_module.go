@@ -0,0 +1,54 @@
+package builtins
+
+import (
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	ReplaceModuleBuiltinName = "replace_module"
+
+	ReplaceModuleBuiltinLocatorArgName = "locator"
+	ReplaceModuleBuiltinModuleArgName  = "module"
+)
+
+// NewReplaceModule creates the replace_module builtin
+//
+// Just like mock, replace_module is not a kurtosis helper since those get passed deep copies of their arguments
+// while the replacement module needs to be the one passed in
+func NewReplaceModule(moduleReplacements *backend.ModuleReplacements) func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var locatorArg starlark.String
+		var moduleArg starlark.Value
+		err := starlark.UnpackArgs(b.Name(), args, kwargs, ReplaceModuleBuiltinLocatorArgName, &locatorArg, ReplaceModuleBuiltinModuleArgName, &moduleArg)
+		if err != nil {
+			return nil, err
+		}
+
+		// The replacement module can be a module or a struct, the members of both are copied
+		// so that the replacement module is not affected by mocks of the replaced one and vice versa
+		members := starlark.StringDict{}
+		switch module := moduleArg.(type) {
+		case *starlarkstruct.Module:
+			for name, member := range module.Members {
+				members[name] = member
+			}
+		case *starlarkstruct.Struct:
+			module.ToStringDict(members)
+		default:
+			return nil, startosis_errors.NewInterpretationError("replace_module: %s must be a module or a struct, got %s", ReplaceModuleBuiltinModuleArgName, moduleArg.Type())
+		}
+
+		// Relative locators are resolved relative to the module that calls replace_module, just like with import_module
+		callerLocator := thread.CallStack().At(1).Pos.Filename()
+		err = moduleReplacements.Replace(callerLocator, locatorArg.GoString(), members)
+		if err != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(err, "replace_module: failed to replace module %s", locatorArg.GoString())
+		}
+
+		return starlark.None, nil
+	}
+}
@@ -0,0 +1,552 @@
+package builtins
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"kurtestosis/cli/core"
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/image_build_spec"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/image_registry_spec"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/nix_build_spec"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/port_spec"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service_directory"
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service_user"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/interpretation_time_value_store"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_instruction/add_service"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_instruction/remove_service"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_type_constructor"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_types/directory"
+	kurtosis_port_spec "github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_types/port_spec"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_types/service_config"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+// Persistent directory sizes are stored in bytes but declared in megabytes
+const megabyte = 1024 * 1024
+
+// Converts a kurtosis object into a starlark ServiceConfig struct,
+// opposite of how ToKurtosisType() method works
+//
+// TODO This feels like it should be included in the kurtosis core
+func toStarlarkServiceConfig(serviceName string, serviceConfig *service.ServiceConfig, testPlan *backend.TestPlan, project *core.KurtestosisProject) (*service_config.ServiceConfig, *startosis_errors.InterpretationError) {
+	var err *startosis_errors.InterpretationError
+
+	image, err := toStarlarkImage(serviceConfig, project)
+	if err != nil {
+		return nil, err
+	}
+
+	ports, err := portSpecMapToStarlarkDict(serviceName, serviceConfig.GetPrivatePorts())
+	if err != nil {
+		return nil, err
+	}
+
+	publicPorts, err := portSpecMapToStarlarkDict(serviceName, serviceConfig.GetPublicPorts())
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := toStarlarkFiles(serviceConfig.GetFilesArtifactsExpansion(), serviceConfig.GetPersistentDirectories())
+	if err != nil {
+		return nil, err
+	}
+
+	envVars, err := stringMapToStarlarkDict(serviceConfig.GetEnvVars())
+	if err != nil {
+		return nil, err
+	}
+
+	readyConditions, err := findReadyConditions(testPlan, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := stringMapToStarlarkDict(serviceConfig.GetLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := toStarlarkUser(serviceConfig.GetUser())
+	if err != nil {
+		return nil, err
+	}
+
+	tolerations, err := toStarlarkTolerations(serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeSelectors, err := stringMapToStarlarkDict(serviceConfig.GetNodeSelectors())
+	if err != nil {
+		return nil, err
+	}
+
+	filesToBeMoved, err := stringMapToStarlarkDict(serviceConfig.GetFilesToBeMoved())
+	if err != nil {
+		return nil, err
+	}
+
+	args := []starlark.Value{
+		image,       // image
+		ports,       // ports
+		publicPorts, // publicPorts
+		files,       // files
+		stringArrayToStarlarkList(serviceConfig.GetEntrypointArgs()), // entrypointArgs
+		stringArrayToStarlarkList(serviceConfig.GetCmdArgs()),        // cmdArgs
+		envVars, // env_vars
+		starlark.String(serviceConfig.GetPrivateIPAddrPlaceholder()),         // private_ip_address_placeholder
+		starlark.MakeUint64(serviceConfig.GetCPUAllocationMillicpus()),       // DEPRECATED cpu_allocation
+		starlark.MakeUint64(serviceConfig.GetMemoryAllocationMegabytes()),    // DEPRECATED memory_allocation
+		starlark.MakeUint64(serviceConfig.GetCPUAllocationMillicpus()),       // max_cpu
+		starlark.MakeUint64(serviceConfig.GetMinCPUAllocationMillicpus()),    // min_cpu
+		starlark.MakeUint64(serviceConfig.GetMemoryAllocationMegabytes()),    // max_memory
+		starlark.MakeUint64(serviceConfig.GetMinMemoryAllocationMegabytes()), // min_memory
+		readyConditions, // ready_conditions
+		labels,          // labels
+		user,            // user
+		tolerations,     // tolerations
+		nodeSelectors,   // node_selectors
+		filesToBeMoved,  // files_to_be_moved
+		starlark.Bool(serviceConfig.GetTiniEnabled()), // tini_enabled
+	}
+
+	argumentDefinitions := service_config.NewServiceConfigType().Arguments
+	argumentValuesSet := builtin_argument.NewArgumentValuesSet(argumentDefinitions, args)
+	kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(service_config.ServiceConfigTypeName, argumentValuesSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service_config.ServiceConfig{
+		KurtosisValueTypeDefault: kurtosisDefaultValue,
+	}, nil
+}
+
+// The image is either a plain image name or one of the image specs
+func toStarlarkImage(serviceConfig *service.ServiceConfig, project *core.KurtestosisProject) (starlark.Value, *startosis_errors.InterpretationError) {
+	imageName := serviceConfig.GetContainerImageName()
+
+	if imageBuildSpec := serviceConfig.GetImageBuildSpec(); imageBuildSpec != nil {
+		return toStarlarkImageBuildSpec(imageName, imageBuildSpec, project)
+	}
+
+	if imageRegistrySpec := serviceConfig.GetImageRegistrySpec(); imageRegistrySpec != nil {
+		return toStarlarkImageSpec(imageRegistrySpec)
+	}
+
+	if nixBuildSpec := serviceConfig.GetNixBuildSpec(); nixBuildSpec != nil {
+		return toStarlarkNixBuildSpec(nixBuildSpec, project)
+	}
+
+	return starlark.String(imageName), nil
+}
+
+func toStarlarkImageBuildSpec(imageName string, imageBuildSpec *image_build_spec.ImageBuildSpec, project *core.KurtestosisProject) (*service_config.ImageBuildSpec, *startosis_errors.InterpretationError) {
+	buildArgs, err := stringMapToStarlarkDict(imageBuildSpec.GetBuildArgs())
+	if err != nil {
+		return nil, err
+	}
+
+	args := []starlark.Value{
+		starlark.String(imageName), // image_name
+		starlark.String(toPackageLocator(project, imageBuildSpec.GetBuildContextDir())), // build_context_dir
+		optionalString(imageBuildSpec.GetBuildFile()),                                   // build_file
+		optionalString(imageBuildSpec.GetTargetStage()),                                 // target_stage
+		buildArgs, // build_args
+	}
+
+	argumentValuesSet := builtin_argument.NewArgumentValuesSet(service_config.NewImageBuildSpecType().Arguments, args)
+	kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(service_config.ImageBuildSpecTypeName, argumentValuesSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service_config.ImageBuildSpec{
+		KurtosisValueTypeDefault: kurtosisDefaultValue,
+	}, nil
+}
+
+func toStarlarkImageSpec(imageRegistrySpec *image_registry_spec.ImageRegistrySpec) (*service_config.ImageSpec, *startosis_errors.InterpretationError) {
+	args := []starlark.Value{
+		starlark.String(imageRegistrySpec.GetImageName()),   // image
+		optionalString(imageRegistrySpec.GetRegistryAddr()), // registry
+		optionalString(imageRegistrySpec.GetUsername()),     // username
+		optionalString(imageRegistrySpec.GetPassword()),     // password
+	}
+
+	argumentValuesSet := builtin_argument.NewArgumentValuesSet(service_config.NewImageSpec().Arguments, args)
+	kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(service_config.ImageSpecTypeName, argumentValuesSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service_config.ImageSpec{
+		KurtosisValueTypeDefault: kurtosisDefaultValue,
+	}, nil
+}
+
+func toStarlarkNixBuildSpec(nixBuildSpec *nix_build_spec.NixBuildSpec, project *core.KurtestosisProject) (*service_config.NixBuildSpec, *startosis_errors.InterpretationError) {
+	// The flake location is relative to the build context
+	flakeLocationDir, relErr := filepath.Rel(nixBuildSpec.GetBuildContextDir(), nixBuildSpec.GetNixFlakeDir())
+	if relErr != nil {
+		flakeLocationDir = nixBuildSpec.GetNixFlakeDir()
+	}
+
+	args := []starlark.Value{
+		starlark.String(filepath.ToSlash(flakeLocationDir)),                           // flake_location_dir
+		starlark.String(toPackageLocator(project, nixBuildSpec.GetBuildContextDir())), // build_context_dir
+		starlark.String(nixBuildSpec.GetImageName()),                                  // image_name
+		optionalString(nixBuildSpec.GetFlakeOutput()),                                 // flake_output
+	}
+
+	argumentValuesSet := builtin_argument.NewArgumentValuesSet(service_config.NewNixBuildSpecType().Arguments, args)
+	kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(service_config.NixBuildSpecTypeName, argumentValuesSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service_config.NixBuildSpec{
+		KurtosisValueTypeDefault: kurtosisDefaultValue,
+	}, nil
+}
+
+// Files artifacts and persistent directories are both declared in the files dict
+//
+// A mount point with a single files artifact is converted to the files artifact name, the same way it's usually declared
+func toStarlarkFiles(filesArtifactsExpansion *service_directory.FilesArtifactsExpansion, persistentDirectories *service_directory.PersistentDirectories) (*starlark.Dict, *startosis_errors.InterpretationError) {
+	files := map[string]starlark.Value{}
+
+	if filesArtifactsExpansion != nil {
+		for mountPoint, artifactNames := range filesArtifactsExpansion.ServiceDirpathsToArtifactIdentifiers {
+			if len(artifactNames) == 1 {
+				files[mountPoint] = starlark.String(artifactNames[0])
+				continue
+			}
+
+			artifactNamesDirectory, err := toStarlarkDirectory(stringArrayToStarlarkList(artifactNames), nil, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			files[mountPoint] = artifactNamesDirectory
+		}
+	}
+
+	if persistentDirectories != nil {
+		for mountPoint, persistentDirectory := range persistentDirectories.ServiceDirpathToPersistentDirectory {
+			persistentKey := starlark.String(persistentDirectory.PersistentKey)
+			size := starlark.MakeInt64(int64(persistentDirectory.Size) / megabyte)
+
+			persistentDirectoryDirectory, err := toStarlarkDirectory(nil, persistentKey, size)
+			if err != nil {
+				return nil, err
+			}
+
+			files[mountPoint] = persistentDirectoryDirectory
+		}
+	}
+
+	dict := starlark.NewDict(len(files))
+	for _, mountPoint := range sortedKeys(files) {
+		err := dict.SetKey(starlark.String(mountPoint), files[mountPoint])
+		if err != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(err, "failed to set key")
+		}
+	}
+
+	return dict, nil
+}
+
+func toStarlarkDirectory(artifactNames starlark.Value, persistentKey starlark.Value, size starlark.Value) (*directory.Directory, *startosis_errors.InterpretationError) {
+	args := []starlark.Value{
+		artifactNames, // artifact_names
+		persistentKey, // persistent_key
+		size,          // size
+	}
+
+	argumentValuesSet := builtin_argument.NewArgumentValuesSet(directory.NewDirectoryType().Arguments, args)
+	kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(directory.DirectoryTypeName, argumentValuesSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &directory.Directory{
+		KurtosisValueTypeDefault: kurtosisDefaultValue,
+	}, nil
+}
+
+func toStarlarkUser(serviceUser *service_user.ServiceUser) (starlark.Value, *startosis_errors.InterpretationError) {
+	if serviceUser == nil {
+		return starlark.None, nil
+	}
+
+	var gid starlark.Value
+	if maybeGid, ok := serviceUser.GetGID(); ok {
+		gid = starlark.MakeInt64(int64(maybeGid))
+	}
+
+	args := []starlark.Value{
+		starlark.MakeInt64(int64(serviceUser.GetUID())), // uid
+		gid, // gid
+	}
+
+	argumentValuesSet := builtin_argument.NewArgumentValuesSet(service_config.NewUserType().Arguments, args)
+	kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(service_config.UserTypeName, argumentValuesSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service_config.User{
+		KurtosisValueTypeDefault: kurtosisDefaultValue,
+	}, nil
+}
+
+func toStarlarkTolerations(serviceConfig *service.ServiceConfig) (*starlark.List, *startosis_errors.InterpretationError) {
+	tolerations := []starlark.Value{}
+	for _, toleration := range serviceConfig.GetTolerations() {
+		var tolerationSeconds starlark.Value
+		if toleration.TolerationSeconds != nil {
+			tolerationSeconds = starlark.MakeInt64(*toleration.TolerationSeconds)
+		}
+
+		args := []starlark.Value{
+			optionalString(toleration.Key),              // key
+			optionalString(string(toleration.Operator)), // operator
+			optionalString(toleration.Value),            // value
+			optionalString(string(toleration.Effect)),   // effect
+			tolerationSeconds,                           // toleration_seconds
+		}
+
+		argumentValuesSet := builtin_argument.NewArgumentValuesSet(service_config.NewTolerationType().Arguments, args)
+		kurtosisDefaultValue, err := kurtosis_type_constructor.CreateKurtosisStarlarkTypeDefault(service_config.TolerationTypeName, argumentValuesSet)
+		if err != nil {
+			return nil, err
+		}
+
+		tolerations = append(tolerations, &service_config.Toleration{
+			KurtosisValueTypeDefault: kurtosisDefaultValue,
+		})
+	}
+
+	return starlark.NewList(tolerations), nil
+}
+
+// Returns the starlark ServiceConfig of a service added by the test plan
+//
+// Services added by add_service have their config stored in the interpretation time value store.
+// Services added by add_services do not, so the config they were declared with is returned instead
+func getStarlarkServiceConfig(
+	interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore,
+	testPlan *backend.TestPlan,
+	project *core.KurtestosisProject,
+	serviceName string,
+) (*service_config.ServiceConfig, *startosis_errors.InterpretationError) {
+	instruction, declaredServiceConfig := findServiceConfigArgument(testPlan, serviceName)
+	if instruction != nil && instruction.Name == add_service.AddServicesBuiltinName {
+		return declaredServiceConfig, nil
+	}
+
+	serviceConfig, err := interpretationTimeValueStore.GetServiceConfig(service.ServiceName(serviceName))
+	if err != nil {
+		return nil, startosis_errors.NewInterpretationError("Failed to get service config for service %s: %v", serviceName, err)
+	}
+
+	return toStarlarkServiceConfig(serviceName, serviceConfig, testPlan, project)
+}
+
+// Returns the names of the services added by the test plan, in the order they were added
+//
+// Updating a service keeps its position, removing it drops it from the list
+func registeredServiceNames(testPlan *backend.TestPlan) []string {
+	serviceNames := []string{}
+	for _, instruction := range testPlan.Instructions() {
+		switch instruction.Name {
+		case add_service.AddServiceBuiltinName:
+			if name, ok := instruction.Argument(0, add_service.ServiceNameArgName).(starlark.String); ok && !slices.Contains(serviceNames, name.GoString()) {
+				serviceNames = append(serviceNames, name.GoString())
+			}
+		case add_service.AddServicesBuiltinName:
+			configs, ok := instruction.Argument(0, add_service.ConfigsArgName).(*starlark.Dict)
+			if !ok {
+				continue
+			}
+
+			for _, key := range configs.Keys() {
+				if name, ok := key.(starlark.String); ok && !slices.Contains(serviceNames, name.GoString()) {
+					serviceNames = append(serviceNames, name.GoString())
+				}
+			}
+		case remove_service.RemoveServiceBuiltinName:
+			if name, ok := instruction.Argument(0, remove_service.ServiceNameArgName).(starlark.String); ok {
+				serviceNames = slices.DeleteFunc(serviceNames, func(serviceName string) bool {
+					return serviceName == name.GoString()
+				})
+			}
+		}
+	}
+
+	return serviceNames
+}
+
+// The ready conditions are not part of the service config kurtosis keeps track of,
+// only the add_service and add_services instructions know about them
+func findReadyConditions(testPlan *backend.TestPlan, serviceName string) (starlark.Value, *startosis_errors.InterpretationError) {
+	_, serviceConfig := findServiceConfigArgument(testPlan, serviceName)
+	if serviceConfig == nil {
+		return starlark.None, nil
+	}
+
+	readyConditions, found, err := kurtosis_type_constructor.ExtractAttrValue[*service_config.ReadyCondition](serviceConfig.KurtosisValueTypeDefault, service_config.ReadyConditionsAttr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return starlark.None, nil
+	}
+
+	return readyConditions, nil
+}
+
+// Returns the last instruction that added a service along with the service config it was called with
+//
+// The service might have been updated, in which case the last config wins
+func findServiceConfigArgument(testPlan *backend.TestPlan, serviceName string) (*backend.TestPlanInstruction, *service_config.ServiceConfig) {
+	instructions := testPlan.Instructions()
+	for i := len(instructions) - 1; i >= 0; i-- {
+		serviceConfig := getServiceConfigArgument(instructions[i], serviceName)
+		if serviceConfig != nil {
+			return instructions[i], serviceConfig
+		}
+	}
+
+	return nil, nil
+}
+
+// Returns the service config a service was added with by an instruction, nil if the instruction did not add the service
+func getServiceConfigArgument(instruction *backend.TestPlanInstruction, serviceName string) *service_config.ServiceConfig {
+	switch instruction.Name {
+	case add_service.AddServiceBuiltinName:
+		name, ok := instruction.Argument(0, add_service.ServiceNameArgName).(starlark.String)
+		if !ok || name.GoString() != serviceName {
+			return nil
+		}
+
+		serviceConfig, _ := instruction.Argument(1, add_service.ServiceConfigArgName).(*service_config.ServiceConfig)
+
+		return serviceConfig
+	case add_service.AddServicesBuiltinName:
+		configs, ok := instruction.Argument(0, add_service.ConfigsArgName).(*starlark.Dict)
+		if !ok {
+			return nil
+		}
+
+		config, found, err := configs.Get(starlark.String(serviceName))
+		if err != nil || !found {
+			return nil
+		}
+
+		serviceConfig, _ := config.(*service_config.ServiceConfig)
+
+		return serviceConfig
+	default:
+		return nil
+	}
+}
+
+// Converts a path on disk back to a locator
+//
+// Paths within the project become absolute locators in the project package, other paths are returned as they are
+func toPackageLocator(project *core.KurtestosisProject, pathOnDisk string) string {
+	relativePath, err := filepath.Rel(project.Path, pathOnDisk)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, "../") {
+		return pathOnDisk
+	}
+
+	return path.Join(project.KurotosisYml.PackageName, filepath.ToSlash(relativePath))
+}
+
+// Unset optional arguments are passed as nil
+func optionalString(value string) starlark.Value {
+	if value == "" {
+		return nil
+	}
+
+	return starlark.String(value)
+}
+
+func stringArrayToStarlarkList(input []string) (output *starlark.List) {
+	values := []starlark.Value{}
+	for _, v := range input {
+		values = append(values, starlark.String(v))
+	}
+
+	return starlark.NewList(values)
+}
+
+// Converts a map to a starlark dict, sorting the keys so that the dict does not depend on the map iteration order
+func stringMapToStarlarkDict(input map[string]string) (*starlark.Dict, *startosis_errors.InterpretationError) {
+	dict := starlark.NewDict(len(input))
+	for _, k := range sortedKeys(input) {
+		err := dict.SetKey(starlark.String(k), starlark.String(input[k]))
+		if err != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(err, "failed to set key")
+		}
+	}
+
+	return dict, nil
+}
+
+func portSpecMapToStarlarkDict(serviceName string, input map[string]*port_spec.PortSpec) (*starlark.Dict, *startosis_errors.InterpretationError) {
+	dict := starlark.NewDict(len(input))
+	for _, k := range sortedKeys(input) {
+		mapped, err := portSpecMapToStarlarkValue(serviceName, input[k])
+		if err != nil {
+			return nil, err
+		}
+
+		setErr := dict.SetKey(starlark.String(k), mapped.Struct)
+		if setErr != nil {
+			return nil, startosis_errors.WrapWithInterpretationError(setErr, "failed to set key")
+		}
+	}
+
+	return dict, nil
+}
+
+func portSpecMapToStarlarkValue(serviceName string, portSpec *port_spec.PortSpec) (*kurtosis_port_spec.PortSpec, *startosis_errors.InterpretationError) {
+	var maybeWaitTimeout string
+	if portSpec.GetWait() != nil {
+		maybeWaitTimeout = portSpec.GetWait().GetTimeout().String()
+	}
+
+	kurtosisPortSpec, err := kurtosis_port_spec.CreatePortSpecUsingGoValues(
+		serviceName,
+		portSpec.GetNumber(),
+		portSpec.GetTransportProtocol(),
+		portSpec.GetMaybeApplicationProtocol(),
+		maybeWaitTimeout,
+		portSpec.GetUrl(),
+	)
+
+	return kurtosisPortSpec, err
+}
+
+func sortedKeys[V any](input map[string]V) []string {
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
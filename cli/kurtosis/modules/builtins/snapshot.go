@@ -0,0 +1,161 @@
+package builtins
+
+import (
+	"fmt"
+	"strings"
+
+	"kurtestosis/cli/core"
+
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	SnapshotBuiltinName = "snapshot"
+
+	SnapshotBuiltinNameArgName  = "name"
+	SnapshotBuiltinValueArgName = "value"
+
+	snapshotIndent = "    "
+)
+
+func NewSnapshot(snapshots *core.Snapshots, reporter *core.TestReporter) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: SnapshotBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              SnapshotBuiltinNameArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+				{
+					Name:              SnapshotBuiltinValueArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.Value],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+			},
+		},
+
+		Capabilities: &snapshotCapabilities{
+			snapshots: snapshots,
+			reporter:  reporter,
+		},
+	}
+}
+
+type snapshotCapabilities struct {
+	snapshots *core.Snapshots
+	reporter  *core.TestReporter
+}
+
+func (builtin *snapshotCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	nameArg, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, SnapshotBuiltinNameArgName)
+	if err != nil {
+		return nil, startosis_errors.WrapWithInterpretationError(err, "An error occurred while extracting the name argument for snapshot builtin")
+	}
+
+	valueArg, err := builtin_argument.ExtractArgumentValue[starlark.Value](arguments, SnapshotBuiltinValueArgName)
+	if err != nil {
+		return nil, startosis_errors.WrapWithInterpretationError(err, "An error occurred while extracting the value argument for snapshot builtin")
+	}
+
+	// A mismatching snapshot fails the test the same way a failed assertion does, without stopping it
+	err = builtin.snapshots.Match(nameArg.GoString(), formatSnapshotValue(valueArg))
+	if err != nil {
+		builtin.reporter.Error(err.Error())
+	}
+
+	return starlark.None, nil
+}
+
+// Formats a starlark value so that it can be compared line by line
+//
+// Strings are stored as they are, collections and structs are spread over multiple lines
+func formatSnapshotValue(value starlark.Value) string {
+	if str, ok := value.(starlark.String); ok {
+		text := str.GoString()
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+
+		return text
+	}
+
+	var builder strings.Builder
+	writeSnapshotValue(&builder, value, "")
+	builder.WriteString("\n")
+
+	return builder.String()
+}
+
+func writeSnapshotValue(builder *strings.Builder, value starlark.Value, indent string) {
+	nestedIndent := indent + snapshotIndent
+
+	switch value := value.(type) {
+	case *starlark.Dict:
+		if value.Len() == 0 {
+			builder.WriteString("{}")
+			return
+		}
+
+		builder.WriteString("{\n")
+		for _, item := range value.Items() {
+			builder.WriteString(nestedIndent + item[0].String() + ": ")
+			writeSnapshotValue(builder, item[1], nestedIndent)
+			builder.WriteString(",\n")
+		}
+		builder.WriteString(indent + "}")
+	case *starlark.List:
+		writeSnapshotSequence(builder, value, "[", "]", indent)
+	case starlark.Tuple:
+		writeSnapshotSequence(builder, value, "(", ")", indent)
+	case *starlarkstruct.Struct:
+		attrNames := value.AttrNames()
+		if len(attrNames) == 0 {
+			builder.WriteString("struct()")
+			return
+		}
+
+		builder.WriteString("struct(\n")
+		for _, attrName := range attrNames {
+			attr, err := value.Attr(attrName)
+			if err != nil {
+				builder.WriteString(fmt.Sprintf("%s%s = <%v>,\n", nestedIndent, attrName, err))
+				continue
+			}
+
+			builder.WriteString(nestedIndent + attrName + " = ")
+			writeSnapshotValue(builder, attr, nestedIndent)
+			builder.WriteString(",\n")
+		}
+		builder.WriteString(indent + ")")
+	default:
+		builder.WriteString(value.String())
+	}
+}
+
+func writeSnapshotSequence(builder *strings.Builder, sequence starlark.Indexable, open string, close string, indent string) {
+	if sequence.Len() == 0 {
+		builder.WriteString(open + close)
+		return
+	}
+
+	builder.WriteString(open + "\n")
+	for i := 0; i < sequence.Len(); i++ {
+		builder.WriteString(indent + snapshotIndent)
+		writeSnapshotValue(builder, sequence.Index(i), indent+snapshotIndent)
+		builder.WriteString(",\n")
+	}
+	builder.WriteString(indent + close)
+}
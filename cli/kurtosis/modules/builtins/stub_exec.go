@@ -0,0 +1,111 @@
+package builtins
+
+import (
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	StubExecBuiltinName = "stub_exec"
+
+	StubExecCommandMatcherArgName = "command_matcher"
+	StubExecExitCodeArgName       = "exit_code"
+	StubExecOutputArgName         = "output"
+)
+
+func NewStubExec(serviceNetwork *backend.KurtestosisServiceNetwork) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: StubExecBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              ServiceNameArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, ServiceNameArgName)
+					},
+				},
+				{
+					Name:              StubExecCommandMatcherArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, StubExecCommandMatcherArgName)
+					},
+				},
+				{
+					Name:              StubExecExitCodeArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.Int],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.Int64InRange(value, StubExecExitCodeArgName, 0, 255)
+					},
+				},
+				{
+					Name:              StubExecOutputArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+			},
+		},
+
+		Capabilities: &stubExecCapabilities{
+			serviceNetwork: serviceNetwork,
+		},
+	}
+}
+
+type stubExecCapabilities struct {
+	serviceNetwork *backend.KurtestosisServiceNetwork
+}
+
+func (builtin *stubExecCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	serviceNameArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, ServiceNameArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	commandMatcherArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, StubExecCommandMatcherArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	exitCode := int64(0)
+	if arguments.IsSet(StubExecExitCodeArgName) {
+		exitCodeArgValue, err := builtin_argument.ExtractArgumentValue[starlark.Int](arguments, StubExecExitCodeArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		exitCode, _ = exitCodeArgValue.Int64()
+	}
+
+	output := ""
+	if arguments.IsSet(StubExecOutputArgName) {
+		outputArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, StubExecOutputArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		output = outputArgValue.GoString()
+	}
+
+	stub, err := backend.NewExecStub(service.ServiceName(serviceNameArgValue.GoString()), commandMatcherArgValue.GoString(), int32(exitCode), output)
+	if err != nil {
+		return nil, startosis_errors.WrapWithInterpretationError(err, "Failed to create exec stub for service %s", serviceNameArgValue.GoString())
+	}
+
+	builtin.serviceNetwork.StubExec(stub)
+
+	return starlark.None, nil
+}
@@ -0,0 +1,186 @@
+package builtins
+
+import (
+	"net/http"
+
+	"kurtestosis/cli/kurtosis/backend"
+
+	"github.com/kurtosis-tech/kurtosis/container-engine-lib/lib/backend_interface/objects/service"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/builtin_argument"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/kurtosis_starlark_framework/kurtosis_helper"
+	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/startosis_errors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	StubHttpBuiltinName = "stub_http"
+
+	StubHttpPortIdArgName     = "port_id"
+	StubHttpEndpointArgName   = "endpoint"
+	StubHttpMethodArgName     = "method"
+	StubHttpStatusCodeArgName = "status_code"
+	StubHttpHeadersArgName    = "headers"
+	StubHttpBodyArgName       = "body"
+)
+
+func NewStubHttp(serviceNetwork *backend.KurtestosisServiceNetwork) *kurtosis_helper.KurtosisHelper {
+	return &kurtosis_helper.KurtosisHelper{
+		KurtosisBaseBuiltin: &kurtosis_starlark_framework.KurtosisBaseBuiltin{
+			Name: StubHttpBuiltinName,
+			Arguments: []*builtin_argument.BuiltinArgument{
+				{
+					Name:              ServiceNameArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, ServiceNameArgName)
+					},
+				},
+				{
+					Name:              StubHttpPortIdArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, StubHttpPortIdArgName)
+					},
+				},
+				{
+					Name:              StubHttpEndpointArgName,
+					IsOptional:        false,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+				{
+					Name:              StubHttpMethodArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.NonEmptyString(value, StubHttpMethodArgName)
+					},
+				},
+				{
+					Name:              StubHttpStatusCodeArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.Int],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return builtin_argument.Int64InRange(value, StubHttpStatusCodeArgName, 100, 599)
+					},
+				},
+				{
+					Name:              StubHttpHeadersArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[*starlark.Dict],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+				{
+					Name:              StubHttpBodyArgName,
+					IsOptional:        true,
+					ZeroValueProvider: builtin_argument.ZeroValueProvider[starlark.String],
+					Validator: func(value starlark.Value) *startosis_errors.InterpretationError {
+						return nil
+					},
+				},
+			},
+		},
+
+		Capabilities: &stubHttpCapabilities{
+			serviceNetwork: serviceNetwork,
+		},
+	}
+}
+
+type stubHttpCapabilities struct {
+	serviceNetwork *backend.KurtestosisServiceNetwork
+}
+
+func (builtin *stubHttpCapabilities) Interpret(locatorOfModuleInWhichThisBuiltInIsBeingCalled string, arguments *builtin_argument.ArgumentValuesSet) (starlark.Value, *startosis_errors.InterpretationError) {
+	serviceNameArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, ServiceNameArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	portIdArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, StubHttpPortIdArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	endpointArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, StubHttpEndpointArgName)
+	if err != nil {
+		return nil, explicitInterpretationError(err)
+	}
+
+	stub := &backend.HttpStub{
+		ServiceName: service.ServiceName(serviceNameArgValue.GoString()),
+		PortId:      portIdArgValue.GoString(),
+		Endpoint:    endpointArgValue.GoString(),
+		Method:      http.MethodGet,
+		StatusCode:  http.StatusOK,
+		Headers:     map[string]string{},
+	}
+
+	if arguments.IsSet(StubHttpMethodArgName) {
+		methodArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, StubHttpMethodArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		stub.Method = methodArgValue.GoString()
+	}
+
+	if arguments.IsSet(StubHttpStatusCodeArgName) {
+		statusCodeArgValue, err := builtin_argument.ExtractArgumentValue[starlark.Int](arguments, StubHttpStatusCodeArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		statusCode, _ := statusCodeArgValue.Int64()
+		stub.StatusCode = int(statusCode)
+	}
+
+	if arguments.IsSet(StubHttpHeadersArgName) {
+		headersArgValue, err := builtin_argument.ExtractArgumentValue[*starlark.Dict](arguments, StubHttpHeadersArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		headers, interpretationErr := starlarkDictToStringMap(headersArgValue, StubHttpHeadersArgName)
+		if interpretationErr != nil {
+			return nil, interpretationErr
+		}
+
+		stub.Headers = headers
+	}
+
+	if arguments.IsSet(StubHttpBodyArgName) {
+		bodyArgValue, err := builtin_argument.ExtractArgumentValue[starlark.String](arguments, StubHttpBodyArgName)
+		if err != nil {
+			return nil, explicitInterpretationError(err)
+		}
+
+		stub.Body = bodyArgValue.GoString()
+	}
+
+	builtin.serviceNetwork.StubHttp(stub)
+
+	return starlark.None, nil
+}
+
+func starlarkDictToStringMap(input *starlark.Dict, argName string) (map[string]string, *startosis_errors.InterpretationError) {
+	output := map[string]string{}
+	for _, item := range input.Items() {
+		key, keyOk := item[0].(starlark.String)
+		value, valueOk := item[1].(starlark.String)
+		if !keyOk || !valueOk {
+			return nil, startosis_errors.NewInterpretationError("Expected %s to be a dict of strings, got %s: %s", argName, item[0].Type(), item[1].Type())
+		}
+
+		output[key.GoString()] = value.GoString()
+	}
+
+	return output, nil
+}
@@ -2,6 +2,8 @@ package modules
 
 import (
 	_ "embed"
+	"kurtestosis/cli/core"
+	"kurtestosis/cli/kurtosis/backend"
 	"kurtestosis/cli/kurtosis/modules/builtins"
 
 	"github.com/kurtosis-tech/kurtosis/core/server/api_container/server/startosis_engine/interpretation_time_value_store"
@@ -12,56 +14,57 @@ import (
 var (
 	//go:embed kurtestosis.star
 	kurtestosisFileSrc string
-	beforeTest         KurtestosisHook
-	afterTest          KurtestosisHook
 )
 
 // Type of a function that can be registered as a before/after hook
 type KurtestosisHook func(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) error
 
+// Hooks that get executed around every kurtosis test
+//
+// Both hooks get passed information about the starlark thread
+// along with context information about the starlark test
+type KurtestosisHooks struct {
+	// BeforeTest hook gets executed before every kurtosis test
+	BeforeTest KurtestosisHook
+	// AfterTest hook gets executed after every kurtosis test
+	AfterTest KurtestosisHook
+}
+
 // LoadKurtestosisModule loads the kurtestosis module.
-func LoadKurtestosisModule(interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore) (starlark.StringDict, error) {
+//
+// Since the hooks are bound to the loaded module, every test needs to load its own instance of the module
+func LoadKurtestosisModule(interpretationTimeValueStore *interpretation_time_value_store.InterpretationTimeValueStore, serviceNetwork *backend.KurtestosisServiceNetwork, testPlan *backend.TestPlan, moduleReplacements *backend.ModuleReplacements, snapshots *core.Snapshots, reporter *core.TestReporter, hooks KurtestosisHooks) (starlark.StringDict, error) {
 	predeclared := starlark.StringDict{
-		"module":                             starlark.NewBuiltin("module", starlarkstruct.MakeModule),
-		"__before_test__":                    starlark.NewBuiltin("__before_test__", runBeforeTest),
-		"__after_test__":                     starlark.NewBuiltin("__after_test__", runAfterTest),
-		builtins.GetServiceConfigBuiltinName: starlark.NewBuiltin(builtins.GetServiceConfigBuiltinName, builtins.NewGetServiceConfig(interpretationTimeValueStore).CreateBuiltin()),
-		builtins.DebugBuiltinName:            starlark.NewBuiltin(builtins.DebugBuiltinName, builtins.NewDebug().CreateBuiltin()),
-		builtins.MockBuiltinName:             starlark.NewBuiltin(builtins.MockBuiltinName, builtins.NewMock().CreateBuiltin()),
+		"module":                                  starlark.NewBuiltin("module", starlarkstruct.MakeModule),
+		"__before_test__":                         starlark.NewBuiltin("__before_test__", createHookBuiltin(hooks.BeforeTest)),
+		"__after_test__":                          starlark.NewBuiltin("__after_test__", createHookBuiltin(hooks.AfterTest)),
+		"__run_test__":                            starlark.NewBuiltin("__run_test__", createRunTestBuiltin(reporter)),
+		builtins.GetServiceConfigBuiltinName:      starlark.NewBuiltin(builtins.GetServiceConfigBuiltinName, builtins.NewGetServiceConfig(interpretationTimeValueStore, testPlan, reporter.TestFunction.TestFile.Project).CreateBuiltin()),
+		builtins.GetServiceConfigsBuiltinName:     starlark.NewBuiltin(builtins.GetServiceConfigsBuiltinName, builtins.NewGetServiceConfigs(interpretationTimeValueStore, testPlan, reporter.TestFunction.TestFile.Project).CreateBuiltin()),
+		builtins.DebugBuiltinName:                 starlark.NewBuiltin(builtins.DebugBuiltinName, builtins.NewDebug(reporter).CreateBuiltin()),
+		builtins.MockBuiltinName:                  starlark.NewBuiltin(builtins.MockBuiltinName, builtins.NewMock()),
+		builtins.SkipBuiltinName:                  starlark.NewBuiltin(builtins.SkipBuiltinName, builtins.NewSkip(reporter).CreateBuiltin()),
+		builtins.XFailBuiltinName:                 starlark.NewBuiltin(builtins.XFailBuiltinName, builtins.NewXFail(reporter).CreateBuiltin()),
+		builtins.StubExecBuiltinName:              starlark.NewBuiltin(builtins.StubExecBuiltinName, builtins.NewStubExec(serviceNetwork).CreateBuiltin()),
+		builtins.StubHttpBuiltinName:              starlark.NewBuiltin(builtins.StubHttpBuiltinName, builtins.NewStubHttp(serviceNetwork).CreateBuiltin()),
+		builtins.HttpRequestsBuiltinName:          starlark.NewBuiltin(builtins.HttpRequestsBuiltinName, builtins.NewHttpRequests(serviceNetwork).CreateBuiltin()),
+		builtins.GetFilesArtifactBuiltinName:      starlark.NewBuiltin(builtins.GetFilesArtifactBuiltinName, builtins.NewGetFilesArtifact(serviceNetwork).CreateBuiltin()),
+		builtins.RenderTemplatesOutputBuiltinName: starlark.NewBuiltin(builtins.RenderTemplatesOutputBuiltinName, builtins.NewRenderTemplatesOutput(serviceNetwork).CreateBuiltin()),
+		builtins.InstructionsBuiltinName:          starlark.NewBuiltin(builtins.InstructionsBuiltinName, builtins.NewInstructions(testPlan).CreateBuiltin()),
+		builtins.SnapshotBuiltinName:              starlark.NewBuiltin(builtins.SnapshotBuiltinName, builtins.NewSnapshot(snapshots, reporter).CreateBuiltin()),
+		builtins.ReplaceModuleBuiltinName:         starlark.NewBuiltin(builtins.ReplaceModuleBuiltinName, builtins.NewReplaceModule(moduleReplacements)),
 	}
 	thread := new(starlark.Thread)
 
 	return starlark.ExecFile(thread, "kurtestosis.star", kurtestosisFileSrc, predeclared)
 }
 
-// Sets the beforeTest hook, overriding the previous value
-//
-// beforeTest hook gets executed before every kurtosis test and gets passed
-// information about the starlark thread along with context information about the starlark test
-func SetBeforeTestFunction(fn KurtestosisHook) {
-	beforeTest = fn
-}
-
-// Sets the afterTest hook, overriding the previous value
-//
-// afterTest hook gets executed after every kurtosis test and gets passed
-// information about the starlark thread along with context information about the starlark test
-func SetAfterTestFunction(fn KurtestosisHook) {
-	afterTest = fn
-}
+func createHookBuiltin(hook KurtestosisHook) func(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if hook == nil {
+			return starlark.None.Truth(), nil
+		}
 
-func runBeforeTest(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	if beforeTest == nil {
-		return starlark.None.Truth(), nil
+		return starlark.None.Truth(), hook(thread, builtin, args, kwargs)
 	}
-
-	return starlark.None.Truth(), beforeTest(thread, builtin, args, kwargs)
-}
-
-func runAfterTest(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	if afterTest == nil {
-		return starlark.None.Truth(), nil
-	}
-
-	return starlark.None.Truth(), afterTest(thread, builtin, args, kwargs)
 }
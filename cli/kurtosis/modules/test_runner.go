@@ -0,0 +1,183 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"kurtestosis/cli/core"
+
+	"go.starlark.net/starlark"
+)
+
+const (
+	// Optional functions in a test file that get called before & after every test function
+	SetupFunctionName    = "setup"
+	TeardownFunctionName = "teardown"
+
+	// Optional functions in a test file that get called before & after the setup and teardown functions
+	//
+	// Since every test function runs in its own isolated interpreter, these also run once for every test
+	SetupModuleFunctionName    = "setup_module"
+	TeardownModuleFunctionName = "teardown_module"
+)
+
+// Creates a builtin that runs a test function from a test module along with its setup and teardown functions
+//
+// Starlark has no way of recovering from errors so the teardown functions need to be called from go.
+// A teardown function is called whenever its matching setup function succeeded (or does not exist),
+// even if the test function itself failed.
+//
+// The first error is returned from the builtin, any subsequent errors are reported using the test reporter
+func createRunTestBuiltin(reporter *core.TestReporter) func(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var plan starlark.Value
+		var mod starlark.HasAttrs
+		var fnName string
+		var paramsIndex starlark.Value = starlark.None
+		err := starlark.UnpackPositionalArgs(builtin.Name(), args, kwargs, 3, &plan, &mod, &fnName, &paramsIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		fn, err := mod.Attr(fnName)
+		if err != nil {
+			return nil, err
+		}
+		if fn == nil {
+			return nil, fmt.Errorf("%s: test module has no function called %s", builtin.Name(), fnName)
+		}
+
+		// Parametrized test functions get passed their parameter set as the second argument
+		fnArgs := starlark.Tuple{plan}
+		if paramsIndex != starlark.None {
+			params, paramsErr := getTestParams(mod, fnName, paramsIndex)
+			if paramsErr != nil {
+				return nil, fmt.Errorf("%s: %w", builtin.Name(), paramsErr)
+			}
+
+			fnArgs = append(fnArgs, params)
+		}
+
+		runner := &testRunner{
+			thread:   thread,
+			reporter: reporter,
+			plan:     plan,
+			mod:      mod,
+		}
+
+		runner.runWithFixture(SetupModuleFunctionName, TeardownModuleFunctionName, func() {
+			runner.runWithFixture(SetupFunctionName, TeardownFunctionName, func() {
+				runner.call(fnName, fn, fnArgs)
+			})
+		})
+
+		if runner.err != nil {
+			return nil, runner.err
+		}
+
+		return starlark.None, nil
+	}
+}
+
+// testRunner keeps track of the errors that occur while running a test function and its fixtures
+type testRunner struct {
+	thread   *starlark.Thread
+	reporter *core.TestReporter
+	plan     starlark.Value
+	mod      starlark.HasAttrs
+
+	// The first error that occurred
+	err error
+}
+
+// Runs the setup function, then the body and finally the teardown function
+//
+// If the setup function fails or skips the test, neither the body nor the teardown function are run
+func (runner *testRunner) runWithFixture(setupName string, teardownName string, body func()) {
+	if !runner.callOptional(setupName) {
+		return
+	}
+
+	body()
+
+	runner.callOptional(teardownName)
+}
+
+// Calls a function from the test module if it exists, returns false if the call failed or was skipped
+func (runner *testRunner) callOptional(fnName string) bool {
+	fn, err := runner.mod.Attr(fnName)
+	if err != nil {
+		return runner.fail(err)
+	}
+
+	if fn == nil {
+		return true
+	}
+
+	return runner.call(fnName, fn, starlark.Tuple{runner.plan})
+}
+
+// Calls a function with args, returns false if the call failed or was skipped
+func (runner *testRunner) call(fnName string, fn starlark.Value, args starlark.Tuple) bool {
+	if _, ok := fn.(starlark.Callable); !ok {
+		return runner.fail(fmt.Errorf("%s is not a function, it's %s", fnName, fn.Type()))
+	}
+
+	_, err := starlark.Call(runner.thread, fn, args, nil)
+	if err != nil {
+		// A function stopped by kurtestosis.skip did not fail, but the ones that come after it still need to be skipped
+		if runner.reporter.IsSkipError(err) {
+			return false
+		}
+
+		return runner.fail(err)
+	}
+
+	return true
+}
+
+// Records an error, always returns false
+func (runner *testRunner) fail(err error) bool {
+	if runner.err == nil {
+		runner.err = err
+
+		return false
+	}
+
+	// Only the first error can be returned, the rest go straight to the reporter
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		runner.reporter.Error(evalErr.Backtrace())
+	} else {
+		runner.reporter.Error(err)
+	}
+
+	return false
+}
+
+// Looks up a parameter set of a parametrized test function in the PARAMS_<fnName> global of the test module
+func getTestParams(mod starlark.HasAttrs, fnName string, paramsIndex starlark.Value) (starlark.Value, error) {
+	index, err := starlark.AsInt32(paramsIndex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter set index %v: %w", paramsIndex, err)
+	}
+
+	paramsGlobalName := core.TestParamsGlobalPrefix + fnName
+	paramsValue, err := mod.Attr(paramsGlobalName)
+	if err != nil {
+		return nil, err
+	}
+	if paramsValue == nil {
+		return nil, fmt.Errorf("test module has no parameter sets called %s", paramsGlobalName)
+	}
+
+	paramsList, ok := paramsValue.(starlark.Indexable)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list, it's %s", paramsGlobalName, paramsValue.Type())
+	}
+
+	if index < 0 || index >= paramsList.Len() {
+		return nil, fmt.Errorf("%s has %d parameter sets, there is no parameter set %d", paramsGlobalName, paramsList.Len(), index)
+	}
+
+	return paramsList.Index(index), nil
+}
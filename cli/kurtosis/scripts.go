@@ -12,10 +12,16 @@ import (
 //
 // This module sets up necessary starlark runtime (especially for the assert module)
 func WrapTestFunction(testFunction *core.TestFunction) (starlark string, mainFunctionName string, jsonInputArgs string) {
+	// Parametrized test functions get passed the index of their parameter set
+	paramsIndex := "None"
+	if testFunction.Parametrized {
+		paramsIndex = fmt.Sprintf("%d", testFunction.ParamsIndex)
+	}
+
 	return fmt.Sprintf(`
 sut = import_module("/%s")
 
 def run(plan):
-	kurtestosis.test(plan, sut, "%s")
-`, testFunction.TestFile.Path, testFunction.Name), "run", startosis_constants.EmptyInputArgs
+	kurtestosis.test(plan, sut, "%s", %s)
+`, testFunction.TestFile.Path, testFunction.Name, paramsIndex), "run", startosis_constants.EmptyInputArgs
 }
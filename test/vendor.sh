@@ -0,0 +1,100 @@
+#!/usr/bin/env bash
+#
+# Runs the vendor command against project--vendor
+#
+# The remote package imported by project--vendor is created as a local git repository
+# in the directory the CLI clones packages to, so the whole test runs offline
+#
+# Usage: ./test/vendor.sh <path to kurtestosis CLI>
+
+set -euo pipefail
+
+CLI=$(realpath "$1")
+SCRIPT_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)
+
+WORK_DIR=$(mktemp -d)
+trap 'rm -rf "$WORK_DIR"' EXIT
+
+TEMP_DIR="$WORK_DIR/temp"
+PACKAGE_DIR="$TEMP_DIR/repos/kurtestosis-fixtures/greeting"
+PROJECT_DIR="$WORK_DIR/project--vendor"
+
+fail() {
+    echo "FAIL: $1"
+    exit 1
+}
+
+# Expects a CLI invocation to fail with a message
+expect_failure() {
+    local message="$1"
+    shift
+
+    local output
+    if output=$("$@" 2>&1); then
+        echo "$output"
+        fail "expected '$*' to fail"
+    fi
+
+    if ! grep -q "$message" <<<"$output"; then
+        echo "$output"
+        fail "expected '$*' to fail with '$message'"
+    fi
+}
+
+git_commit() {
+    git -C "$PACKAGE_DIR" add -A
+    git -C "$PACKAGE_DIR" -c user.name=kurtestosis -c user.email=kurtestosis@localhost commit -q -m "$1"
+}
+
+echo "Creating the remote package"
+mkdir -p "$PACKAGE_DIR"
+git -C "$PACKAGE_DIR" init -q
+cat >"$PACKAGE_DIR/kurtosis.yml" <<EOF
+name: github.com/kurtestosis-fixtures/greeting
+EOF
+cat >"$PACKAGE_DIR/main.star" <<EOF
+def greet(name):
+    return "hello " + name
+EOF
+git_commit "Add greeting"
+git -C "$PACKAGE_DIR" tag v1.0
+COMMIT=$(git -C "$PACKAGE_DIR" rev-parse HEAD)
+
+cp -r "$SCRIPT_DIR/project--vendor" "$PROJECT_DIR"
+
+echo "Vendoring"
+"$CLI" vendor "$PROJECT_DIR" --temp-dir "$TEMP_DIR"
+
+[ -f "$PROJECT_DIR/vendor/kurtestosis-fixtures/greeting/main.star" ] || fail "package was not vendored"
+grep -q "\"commit\": \"$COMMIT\"" "$PROJECT_DIR/kurtestosis.lock" || fail "lockfile does not pin commit $COMMIT"
+grep -q '"hash": "sha256:' "$PROJECT_DIR/kurtestosis.lock" || fail "lockfile does not record the package hash"
+
+echo "Running tests from the vendor directory"
+"$CLI" "$PROJECT_DIR" --temp-dir "$TEMP_DIR" --offline
+
+echo "Running tests with a modified vendor directory"
+cp "$PROJECT_DIR/vendor/kurtestosis-fixtures/greeting/main.star" "$WORK_DIR/main.star"
+echo "# modified" >>"$PROJECT_DIR/vendor/kurtestosis-fixtures/greeting/main.star"
+expect_failure "does not match kurtestosis.lock" "$CLI" "$PROJECT_DIR" --temp-dir "$TEMP_DIR" --offline
+cp "$WORK_DIR/main.star" "$PROJECT_DIR/vendor/kurtestosis-fixtures/greeting/main.star"
+
+echo "Running tests with a package missing from the lockfile"
+mkdir -p "$PROJECT_DIR/vendor/kurtestosis-fixtures/unpinned"
+cp "$PACKAGE_DIR/main.star" "$PROJECT_DIR/vendor/kurtestosis-fixtures/unpinned/main.star"
+expect_failure "vendored but not pinned" "$CLI" "$PROJECT_DIR" --temp-dir "$TEMP_DIR" --offline
+rm -rf "$PROJECT_DIR/vendor/kurtestosis-fixtures/unpinned"
+
+echo "Running tests with a versioned import that does not match the lockfile"
+sed -i 's|kurtestosis-fixtures/greeting/main.star|kurtestosis-fixtures/greeting/main.star@v2.0|' "$PROJECT_DIR/sut.star"
+expect_failure 'is vendored at version' "$CLI" "$PROJECT_DIR" --temp-dir "$TEMP_DIR" --offline
+
+echo "Vendoring a versioned import"
+git -C "$PACKAGE_DIR" checkout -q v1.0
+sed -i 's|main.star@v2.0|main.star@v1.0|' "$PROJECT_DIR/sut.star"
+"$CLI" vendor "$PROJECT_DIR" --temp-dir "$TEMP_DIR"
+grep -q '"version": "v1.0"' "$PROJECT_DIR/kurtestosis.lock" || fail "lockfile does not record version v1.0"
+
+echo "Running tests with a versioned import"
+"$CLI" "$PROJECT_DIR" --temp-dir "$TEMP_DIR" --offline
+
+echo "Vendor tests passed"